@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	"github.com/conductorone/baton-sdk/pkg/logging"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	outputFormat  = "output"
+	showSensitive = "show-sensitive"
+	providerLabel = "provider-label"
+
+	outputTable = "table"
+	outputJSON  = "json"
+
+	explorePageSize = 100
+)
+
+// explorer runs the read-only calls of the explore commands against one Galileo-FT provider.
+type explorer struct {
+	client    *galileo.Client
+	json      bool
+	sensitive bool
+	out       io.Writer
+}
+
+// groupsCommand returns the commands exploring the groups of the Galileo-FT tenant.
+func groupsCommand(ctx context.Context, v *viper.Viper) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "groups",
+		Short: "Explore the groups of the Galileo-FT tenant",
+		Args:  cobra.NoArgs,
+	}
+
+	exploreFlags(cmd)
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "tree",
+			Short: "Print the root groups and the groups below them",
+			Args:  cobra.NoArgs,
+			RunE: exploreRun(ctx, v, func(ctx context.Context, e *explorer, _ []string) error {
+				return e.groupsTree(ctx)
+			}),
+		},
+		&cobra.Command{
+			Use:   "members <group-id>",
+			Short: "Print the accounts of a group",
+			Args:  cobra.ExactArgs(1),
+			RunE: exploreRun(ctx, v, func(ctx context.Context, e *explorer, args []string) error {
+				return e.groupMembers(ctx, args[0])
+			}),
+		},
+	)
+
+	return cmd
+}
+
+// accountsCommand returns the commands exploring the accounts of the Galileo-FT tenant.
+func accountsCommand(ctx context.Context, v *viper.Viper) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "accounts",
+		Short: "Explore the accounts of the Galileo-FT tenant",
+		Args:  cobra.NoArgs,
+	}
+
+	exploreFlags(cmd)
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "show <prn>",
+			Short: "Print the customer of an account",
+			Args:  cobra.ExactArgs(1),
+			RunE: exploreRun(ctx, v, func(ctx context.Context, e *explorer, args []string) error {
+				return e.showAccount(ctx, args[0])
+			}),
+		},
+		&cobra.Command{
+			Use:   "related <prn>",
+			Short: "Print the accounts related to a primary account",
+			Args:  cobra.ExactArgs(1),
+			RunE: exploreRun(ctx, v, func(ctx context.Context, e *explorer, args []string) error {
+				return e.relatedAccounts(ctx, args[0])
+			}),
+		},
+	)
+
+	return cmd
+}
+
+func exploreFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String(outputFormat, outputTable, "Output format: table or json. ($BATON_OUTPUT)")
+	cmd.PersistentFlags().Bool(showSensitive, false, "Print the personal data of customers and contacts and the full account numbers instead of redacting them. ($BATON_SHOW_SENSITIVE)")
+	cmd.PersistentFlags().String(providerLabel, "", "Label of the provider to explore, when several providers are configured. ($BATON_PROVIDER_LABEL)")
+}
+
+// exploreRun returns the run function of an explore command, calling run with an explorer of the configured provider.
+func exploreRun(ctx context.Context, v *viper.Viper, run func(ctx context.Context, e *explorer, args []string) error) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		err := v.BindPFlags(cmd.Flags())
+		if err != nil {
+			return err
+		}
+
+		ctx, err := logging.Init(ctx, logging.WithLogFormat(v.GetString("log-format")), logging.WithLogLevel(v.GetString("log-level")))
+		if err != nil {
+			return err
+		}
+
+		e, err := newExplorer(ctx, v, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+
+		return run(ctx, e, args)
+	}
+}
+
+func newExplorer(ctx context.Context, v *viper.Viper, out io.Writer) (*explorer, error) {
+	format := v.GetString(outputFormat)
+	if format != outputTable && format != outputJSON {
+		return nil, fmt.Errorf("invalid --%s %q: must be %s or %s", outputFormat, format, outputTable, outputJSON)
+	}
+
+	cfg, err := exploreProvider(v)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := getTransportConfig(v)
+	httpClient, err := galileo.NewHTTPClient(ctx, &transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client: %w", err)
+	}
+
+	client, err := galileo.NewClient(httpClient, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &explorer{
+		client:    client,
+		json:      format == outputJSON,
+		sensitive: v.GetBool(showSensitive),
+		out:       out,
+	}, nil
+}
+
+// exploreProvider returns the configuration of the provider to explore: the provider of the given label,
+// or the only configured provider.
+func exploreProvider(v *viper.Viper) (*galileo.Config, error) {
+	cfgs, err := getProviderConfigs(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfgs) == 0 {
+		return nil, fmt.Errorf("no provider configured: set --%s or --%s", providerID, providers)
+	}
+
+	label := v.GetString(providerLabel)
+	if label == "" {
+		if len(cfgs) > 1 {
+			return nil, fmt.Errorf("%d providers configured: select one with --%s", len(cfgs), providerLabel)
+		}
+
+		return cfgs[0], nil
+	}
+
+	for _, cfg := range cfgs {
+		if cfg.Label == label {
+			return cfg, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no provider labeled %q", label)
+}
+
+// groupNode is a group of the tree printed by groups tree.
+type groupNode struct {
+	ID           string       `json:"group_id"`
+	Name         string       `json:"group_name"`
+	ExternalID   string       `json:"external_id,omitempty"`
+	LegalName    string       `json:"business_legal_name,omitempty"`
+	Business     string       `json:"doing_business_as,omitempty"`
+	ContactName  string       `json:"primary_contact_name,omitempty"`
+	ContactEmail string       `json:"primary_contact_email,omitempty"`
+	Children     []*groupNode `json:"children,omitempty"`
+}
+
+func (e *explorer) groupsTree(ctx context.Context) error {
+	var roots []*groupNode
+	for page := uint(1); ; page++ {
+		groups, pages, err := e.client.ListRootGroups(ctx, galileo.NewPaginationVars(page, explorePageSize))
+		if err != nil {
+			return fmt.Errorf("failed to list root groups: %w", err)
+		}
+
+		for _, g := range groups {
+			hierarchy, err := e.client.GetGroupHierarchy(ctx, g.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get hierarchy of group %s: %w", g.ID, err)
+			}
+
+			roots = append(roots, &groupNode{
+				ID:           g.ID,
+				Name:         g.Name,
+				ExternalID:   g.ExternalID,
+				LegalName:    g.LegalName,
+				Business:     g.Business,
+				ContactName:  e.redactName(g.ContactName),
+				ContactEmail: e.redactEmail(g.ContactEmail),
+				Children:     hierarchyNodes(hierarchy),
+			})
+		}
+
+		if page >= pages {
+			break
+		}
+	}
+
+	if e.json {
+		return e.printJSON(roots)
+	}
+
+	w := e.table("GROUP", "NAME", "EXTERNAL ID", "BUSINESS", "CONTACT")
+	var printNodes func(nodes []*groupNode, depth int)
+	printNodes = func(nodes []*groupNode, depth int) {
+		for _, n := range nodes {
+			business := n.Business
+			if business == "" {
+				business = n.LegalName
+			}
+
+			contact := strings.TrimSpace(fmt.Sprintf("%s %s", n.ContactName, n.ContactEmail))
+			row(w, strings.Repeat("  ", depth)+n.ID, n.Name, n.ExternalID, business, contact)
+			printNodes(n.Children, depth+1)
+		}
+	}
+	printNodes(roots, 0)
+
+	return w.Flush()
+}
+
+func hierarchyNodes(hierarchy []galileo.GroupHierarchy) []*groupNode {
+	var rv []*groupNode
+	for _, h := range hierarchy {
+		rv = append(rv, &groupNode{
+			ID:       h.ID,
+			Name:     h.Name,
+			Children: hierarchyNodes(h.Children),
+		})
+	}
+
+	return rv
+}
+
+func (e *explorer) groupMembers(ctx context.Context, groupID string) error {
+	members, err := e.client.ListGroupMembers(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to list members of group %s: %w", groupID, err)
+	}
+
+	if e.json {
+		return e.printJSON(members)
+	}
+
+	w := e.table("ACCOUNT")
+	for _, accID := range members.AccountIDs {
+		row(w, accID)
+	}
+
+	return w.Flush()
+}
+
+func (e *explorer) showAccount(ctx context.Context, accID string) error {
+	customer, err := e.client.GetCustomer(ctx, accID)
+	if err != nil {
+		return fmt.Errorf("failed to get customer of account %s: %w", accID, err)
+	}
+
+	if customer == nil {
+		return fmt.Errorf("account %s has no customer", accID)
+	}
+
+	if !e.sensitive {
+		customer = redactCustomer(customer)
+	}
+
+	if e.json {
+		return e.printJSON(customer)
+	}
+
+	w := e.table("FIELD", "VALUE")
+	for _, f := range []struct{ name, value string }{
+		{"customer_id", customer.CustomerID},
+		{"external_id", customer.ExternalID},
+		{"first_name", customer.FirstName},
+		{"middle_name", customer.MiddleName},
+		{"last_name", customer.LastName},
+		{"email", customer.Email},
+		{"home_phone", customer.HomePhone},
+		{"mobile_phone", customer.MobilePhone},
+		{"address_1", customer.Address1},
+		{"address_2", customer.Address2},
+		{"city", customer.City},
+		{"state", customer.State},
+		{"postal_code", customer.PostalCode},
+		{"country_code", customer.CountryCode},
+		{"cip_status", customer.CIPStatus},
+		{"kyc_result", customer.KYCResult},
+		{"ofac_match", customer.OFACMatch},
+	} {
+		row(w, f.name, f.value)
+	}
+
+	return w.Flush()
+}
+
+func (e *explorer) relatedAccounts(ctx context.Context, accID string) error {
+	accounts, err := e.client.ListRelatedAccounts(ctx, accID)
+	if err != nil {
+		return fmt.Errorf("failed to list accounts related to %s: %w", accID, err)
+	}
+
+	if !e.sensitive {
+		for i := range accounts {
+			accounts[i].AccNumber = redactTail(accounts[i].AccNumber)
+		}
+	}
+
+	if e.json {
+		return e.printJSON(accounts)
+	}
+
+	w := e.table("ACCOUNT", "ACCOUNT NUMBER", "PRODUCT", "STATUS", "ACTIVE")
+	for _, a := range accounts {
+		row(w, a.ID, a.AccNumber, a.ProdID, a.Status, a.Active)
+	}
+
+	return w.Flush()
+}
+
+func (e *explorer) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(e.out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(value)
+}
+
+// table returns a writer aligning the rows in columns, with the header already written.
+func (e *explorer) table(header ...string) *tabwriter.Writer {
+	w := tabwriter.NewWriter(e.out, 0, 0, 2, ' ', 0)
+	row(w, header...)
+
+	return w
+}
+
+func row(w io.Writer, columns ...string) {
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+}
+
+func (e *explorer) redactName(value string) string {
+	if e.sensitive {
+		return value
+	}
+
+	return redactName(value)
+}
+
+func (e *explorer) redactEmail(value string) string {
+	if e.sensitive {
+		return value
+	}
+
+	return redactEmail(value)
+}
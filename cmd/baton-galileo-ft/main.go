@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/conductorone/baton-galileo-ft/pkg/connector"
 	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	"github.com/conductorone/baton-sdk/pkg/cli"
 	configSchema "github.com/conductorone/baton-sdk/pkg/config"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/connectorrunner"
 	"github.com/conductorone/baton-sdk/pkg/field"
 	"github.com/conductorone/baton-sdk/pkg/types"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
@@ -22,44 +27,348 @@ const (
 	apiLogin      = "api-login"
 	apiTransKey   = "api-trans-key"
 	providerID    = "provider-id"
+	environment   = "environment"
 	hostname      = "hostname"
 	baseURL       = "base-url"
+	useExternalID = "use-external-id"
+	providers     = "providers"
+	clientCert    = "client-cert"
+	clientKey     = "client-key"
+	caBundle      = "ca-bundle"
+	httpProxy     = "http-proxy"
+	httpsProxy    = "https-proxy"
+	proxyUsername = "proxy-username"
+	proxyPassword = "proxy-password"
+	maxIdleConns  = "max-idle-conns-per-host"
+	idleTimeout   = "idle-conn-timeout"
+	cacheTTL      = "cache-ttl"
+	cacheSize     = "cache-max-entries"
+	progressEvery = "progress-interval"
+	syncReport    = "sync-report"
+	maxFailures   = "max-account-failures"
+	placeholders  = "placeholder-users"
+	includeGroups = "include-groups"
+	excludeGroups = "exclude-groups"
+	includePaths  = "include-paths"
+	excludePaths  = "exclude-paths"
+	includeProds  = "include-products"
+	excludeProds  = "exclude-products"
+	ticketStore   = "ticket-store"
+	ticketWebhook = "ticket-webhook-url"
+	ticketToken   = "ticket-webhook-token"
+	eventsDir     = "events-dir"
+	usageWindow   = "usage-window"
+	usageMaxPages = "usage-max-pages"
+	usageMaxCalls = "usage-max-calls"
+	usageTimezone = "usage-timezone"
+	identityProf  = "identity-profile"
+	identityNames = "identity-fields"
+	kycVerified   = "kyc-verified"
+	businesses    = "business-resources"
+	dryRun        = "dry-run"
 )
 
+// The fields connecting to Galileo-FT are persistent, so they are shared with the explore commands.
 var (
 	apiLoginField = field.StringField(
 		apiLogin,
-		field.WithRequired(true),
 		field.WithDescription("The username provided by Galileo-FT for API access."),
+		field.WithPersistent(true),
 	)
 	apiTransKeyField = field.StringField(
 		apiTransKey,
-		field.WithRequired(true),
 		field.WithDescription("The password provided by Galileo-FT, used alongside the api-login."),
+		field.WithPersistent(true),
+	)
+	environmentField = field.SelectField(
+		environment,
+		galileo.Environments,
+		field.WithDisplayName("Environment"),
+		field.WithDescription("The Galileo-FT environment to sync: sandbox, production or custom (requires --base-url)."),
+		field.WithPersistent(true),
 	)
 	hostnameField = field.StringField(
 		hostname,
-		field.WithDescription("URL hostname for production hostname (deprecated: use --base-url instead)."),
+		field.WithDescription("URL hostname of the custom environment (deprecated: use --base-url instead)."),
+		field.WithPersistent(true),
 	)
 	baseURLField = field.StringField(
 		baseURL,
-		field.WithDescription("Override the Galileo FT API URL (for testing)."),
-		field.WithHidden(true),
+		field.WithDisplayName("Base URL"),
+		field.WithDescription("The Galileo-FT API URL of the custom environment, which must be selected with --environment custom."),
+		field.WithPersistent(true),
 	)
 	providerIDField = field.StringField(
 		providerID,
-		field.WithRequired(true),
 		field.WithDescription("A unique identifier from Galileo-FT representing your organization, used for tracking transactions and data."),
+		field.WithPersistent(true),
+	)
+	useExternalIDField = field.BoolField(
+		useExternalID,
+		field.WithDisplayName("Use external ID"),
+		field.WithDescription("Identify users by their Galileo external ID instead of the account PRN, when available."),
+	)
+	providersField = field.StringField(
+		providers,
+		field.WithDisplayName("Providers"),
+		field.WithDescription(
+			`JSON list of additional Galileo-FT programs to sync, each with a unique "label" and its own "provider-id", "api-login" and "api-trans-key" `+
+				`(optionally an "environment", with a "base-url" for the custom one). Resources of labeled programs are prefixed with the label.`,
+		),
+		field.WithIsSecret(true),
+		field.WithPersistent(true),
+	)
+	clientCertField = field.StringField(
+		clientCert,
+		field.WithDisplayName("Client certificate"),
+		field.WithDescription("Client certificate for mutual TLS with Galileo-FT, as a file path or inline PEM."),
+		field.WithPersistent(true),
+	)
+	clientKeyField = field.StringField(
+		clientKey,
+		field.WithDisplayName("Client key"),
+		field.WithDescription("Private key of the client certificate, as a file path or inline PEM."),
+		field.WithIsSecret(true),
+		field.WithPersistent(true),
+	)
+	caBundleField = field.StringField(
+		caBundle,
+		field.WithDisplayName("CA bundle"),
+		field.WithDescription("Additional CA certificates trusted for the Galileo-FT API, as a file path or inline PEM."),
+		field.WithPersistent(true),
+	)
+	httpProxyField = field.StringField(
+		httpProxy,
+		field.WithDisplayName("HTTP proxy"),
+		field.WithDescription("URL of the proxy used for HTTP requests, and for HTTPS requests when no HTTPS proxy is set."),
+		field.WithPersistent(true),
+	)
+	httpsProxyField = field.StringField(
+		httpsProxy,
+		field.WithDisplayName("HTTPS proxy"),
+		field.WithDescription("URL of the proxy used for HTTPS requests."),
+		field.WithPersistent(true),
+	)
+	proxyUsernameField = field.StringField(
+		proxyUsername,
+		field.WithDisplayName("Proxy username"),
+		field.WithDescription("Username used to authenticate to the proxy."),
+		field.WithPersistent(true),
+	)
+	proxyPasswordField = field.StringField(
+		proxyPassword,
+		field.WithDisplayName("Proxy password"),
+		field.WithDescription("Password used to authenticate to the proxy."),
+		field.WithIsSecret(true),
+		field.WithPersistent(true),
+	)
+	maxIdleConnsField = field.IntField(
+		maxIdleConns,
+		field.WithDisplayName("Max idle connections"),
+		field.WithDescription("Maximum number of idle connections kept open to the Galileo-FT API."),
+		field.WithDefaultValue(galileo.DefaultMaxIdleConnsPerHost),
+		field.WithPersistent(true),
+	)
+	idleTimeoutField = field.IntField(
+		idleTimeout,
+		field.WithDisplayName("Idle connection timeout"),
+		field.WithDescription("Seconds an idle connection is kept open, keep it below the idle timeout of the proxy."),
+		field.WithDefaultValue(int(galileo.DefaultIdleConnTimeout.Seconds())),
+		field.WithPersistent(true),
+	)
+	cacheTTLField = field.IntField(
+		cacheTTL,
+		field.WithDisplayName("Cache TTL"),
+		field.WithDescription("Seconds Galileo-FT responses are cached within a sync, 0 disables the cache."),
+		field.WithDefaultValue(int(galileo.DefaultCacheTTL.Seconds())),
+	)
+	cacheSizeField = field.IntField(
+		cacheSize,
+		field.WithDisplayName("Cache max entries"),
+		field.WithDescription("Maximum number of Galileo-FT responses kept in memory, when the sync provides no session store."),
+		field.WithDefaultValue(galileo.DefaultCacheMaxEntries),
+	)
+	progressEveryField = field.IntField(
+		progressEvery,
+		field.WithDisplayName("Progress interval"),
+		field.WithDescription("Seconds between sync progress logs, 0 disables them."),
+		field.WithDefaultValue(60),
+	)
+	syncReportField = field.StringField(
+		syncReport,
+		field.WithDisplayName("Sync report"),
+		field.WithDescription("Path of a JSON file the summary of the sync is written to."),
+	)
+	maxFailuresField = field.IntField(
+		maxFailures,
+		field.WithDisplayName("Max account failures"),
+		field.WithDescription("Number of accounts that can fail with an account specific error (an account without customer or that Galileo reports as not found) and be skipped before the sync fails."),
+	)
+	placeholdersField = field.BoolField(
+		placeholders,
+		field.WithDisplayName("Placeholder users"),
+		field.WithDescription("Sync a user with minimal data for the skipped accounts, marked as a placeholder in its status details and profile."),
+	)
+	includeGroupsField = field.StringSliceField(
+		includeGroups,
+		field.WithDisplayName("Include groups"),
+		field.WithDescription("IDs or external IDs of the groups whose subtrees are synced. IDs of labeled providers are prefixed with the label."),
 	)
-	configurationFields = []field.SchemaField{apiLoginField, apiTransKeyField, providerIDField, hostnameField, baseURLField}
+	excludeGroupsField = field.StringSliceField(
+		excludeGroups,
+		field.WithDisplayName("Exclude groups"),
+		field.WithDescription("IDs or external IDs of the groups whose subtrees are not synced. IDs of labeled providers are prefixed with the label."),
+	)
+	includePathsField = field.StringSliceField(
+		includePaths,
+		field.WithDisplayName("Include paths"),
+		field.WithDescription("Paths of the groups whose subtrees are synced, the names of the groups from their root group separated by /. Paths of labeled providers are prefixed with the label."),
+	)
+	excludePathsField = field.StringSliceField(
+		excludePaths,
+		field.WithDisplayName("Exclude paths"),
+		field.WithDescription("Paths of the groups whose subtrees are not synced, the names of the groups from their root group separated by /. Paths of labeled providers are prefixed with the label."),
+	)
+	includeProdsField = field.StringSliceField(
+		includeProds,
+		field.WithDisplayName("Include products"),
+		field.WithDescription("IDs of the products whose accounts are synced, group members and related accounts alike. IDs of labeled providers are prefixed with the label."),
+	)
+	excludeProdsField = field.StringSliceField(
+		excludeProds,
+		field.WithDisplayName("Exclude products"),
+		field.WithDescription("IDs of the products whose accounts are not synced, group members and related accounts alike. IDs of labeled providers are prefixed with the label."),
+	)
+	ticketStoreField = field.StringField(
+		ticketStore,
+		field.WithDisplayName("Ticket store"),
+		field.WithDescription("Directory the program change tickets are written to, with --ticketing."),
+	)
+	ticketWebhookField = field.StringField(
+		ticketWebhook,
+		field.WithDisplayName("Ticket webhook URL"),
+		field.WithDescription("URL the program change tickets are posted to with --ticketing, their status is read from the URL followed by the ticket ID."),
+	)
+	ticketTokenField = field.StringField(
+		ticketToken,
+		field.WithDisplayName("Ticket webhook token"),
+		field.WithDescription("Bearer token sent to the ticket webhook."),
+		field.WithIsSecret(true),
+	)
+	eventsDirField = field.StringField(
+		eventsDir,
+		field.WithDisplayName("Events directory"),
+		field.WithDescription("Directory of the Galileo-FT events queued by the webhook command, fed to the event feed of the connector."),
+	)
+	usageWindowField = field.IntField(
+		usageWindow,
+		field.WithDisplayName("Usage window"),
+		field.WithDescription("Days of transaction and authorization history summarized on every user (count, last use, top MCCs), 0 disables the usage summaries."),
+	)
+	usageMaxPagesField = field.IntField(
+		usageMaxPages,
+		field.WithDisplayName("Usage max pages"),
+		field.WithDescription("Maximum pages of 100 transactions or authorizations read per account, the summary is marked truncated beyond."),
+		field.WithDefaultValue(5),
+	)
+	usageMaxCallsField = field.IntField(
+		usageMaxCalls,
+		field.WithDisplayName("Usage max calls"),
+		field.WithDescription("Maximum history calls per sync, accounts synced once it is reached have no usage summary. 0 removes the limit."),
+		field.WithDefaultValue(5000),
+	)
+	usageTimezoneField = field.StringField(
+		usageTimezone,
+		field.WithDisplayName("Usage time zone"),
+		field.WithDescription("IANA time zone of the transaction and authorization timestamps of the program, e.g. America/Denver."),
+		field.WithDefaultValue("UTC"),
+	)
+	identityProfField = field.BoolField(
+		identityProf,
+		field.WithDisplayName("Identity profile"),
+		field.WithDescription("Add the identity verification state of the customer (CIP status, KYC result, OFAC match) to the user profile."),
+	)
+	identityNamesField = field.StringSliceField(
+		identityNames,
+		field.WithDisplayName("Identity fields"),
+		field.WithDescription("Names of the profile fields of the identity verification state in the account overview of the program, as cip_status=name, kyc_result=name or ofac_match=name pairs. Fields not given keep their default name: cip_status, kyc_result and ofac_match."),
+	)
+	kycVerifiedField = field.BoolField(
+		kycVerified,
+		field.WithDisplayName("KYC verified entitlement"),
+		field.WithDescription("Sync a kyc-verified entitlement per provider, granted to the users whose customer passed the CIP and KYC verifications without an OFAC match. Their verification state is added to the user profile."),
+	)
+	businessesField = field.BoolField(
+		businesses,
+		field.WithDisplayName("Business resources"),
+		field.WithDescription("Sync root groups as business resources, with their department groups under them. Changes the resource type of root groups."),
+	)
+	dryRunField = field.BoolField(
+		dryRun,
+		field.WithDisplayName("Dry run"),
+		field.WithDescription("Validate grants, revokes and actions and log the Galileo-FT requests they would send, without changing Galileo-FT."),
+	)
+	configurationFields = []field.SchemaField{
+		apiLoginField,
+		apiTransKeyField,
+		providerIDField,
+		environmentField,
+		hostnameField,
+		baseURLField,
+		useExternalIDField,
+		providersField,
+		clientCertField,
+		clientKeyField,
+		caBundleField,
+		httpProxyField,
+		httpsProxyField,
+		proxyUsernameField,
+		proxyPasswordField,
+		maxIdleConnsField,
+		idleTimeoutField,
+		cacheTTLField,
+		cacheSizeField,
+		progressEveryField,
+		syncReportField,
+		maxFailuresField,
+		placeholdersField,
+		includeGroupsField,
+		excludeGroupsField,
+		includePathsField,
+		excludePathsField,
+		includeProdsField,
+		excludeProdsField,
+		ticketStoreField,
+		ticketWebhookField,
+		ticketTokenField,
+		eventsDirField,
+		usageWindowField,
+		usageMaxPagesField,
+		usageMaxCallsField,
+		usageTimezoneField,
+		identityProfField,
+		identityNamesField,
+		kycVerifiedField,
+		businessesField,
+		dryRunField,
+	}
+	fieldRelationships = []field.SchemaFieldRelationship{
+		field.FieldsRequiredTogether(apiLoginField, apiTransKeyField, providerIDField),
+		field.FieldsAtLeastOneUsed(providerIDField, providersField),
+		field.FieldsRequiredTogether(clientCertField, clientKeyField),
+		field.FieldsRequiredTogether(proxyUsernameField, proxyPasswordField),
+		field.FieldsMutuallyExclusive(ticketStoreField, ticketWebhookField),
+		field.FieldsDependentOn([]field.SchemaField{ticketTokenField}, []field.SchemaField{ticketWebhookField}),
+	}
 )
 
 func main() {
 	ctx := context.Background()
-	_, cmd, err := configSchema.DefineConfiguration(ctx,
+	v, cmd, err := configSchema.DefineConfigurationV2(ctx,
 		connectorName,
 		getConnector,
-		field.NewConfiguration(configurationFields),
+		field.NewConfiguration(configurationFields, field.WithConstraints(fieldRelationships...)),
+		connectorrunner.WithSessionStoreEnabled(),
 	)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -67,6 +376,8 @@ func main() {
 	}
 
 	cmd.Version = version
+	cmd.AddCommand(webhookCommand(ctx, v), groupsCommand(ctx, v), accountsCommand(ctx, v))
+
 	err = cmd.Execute()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -74,21 +385,72 @@ func main() {
 	}
 }
 
-func getConnector(ctx context.Context, cfg *viper.Viper) (types.ConnectorServer, error) {
+func getConnector(ctx context.Context, cfg *viper.Viper, runTimeOpts cli.RunTimeOpts) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
-	cb, err := connector.New(ctx, &galileo.Config{
-		Hostname:    cfg.GetString(hostname),
-		BaseURL:     cfg.GetString(baseURL),
-		APILogin:    cfg.GetString(apiLogin),
-		APITransKey: cfg.GetString(apiTransKey),
-		ProviderID:  cfg.GetString(providerID),
+
+	providerConfigs, err := getProviderConfigs(cfg)
+	if err != nil {
+		l.Error("error parsing providers", zap.Error(err))
+		return nil, err
+	}
+
+	usageLocation, err := time.LoadLocation(cfg.GetString(usageTimezone))
+	if err != nil {
+		l.Error("invalid usage time zone", zap.Error(err))
+		return nil, fmt.Errorf("invalid --%s: %w", usageTimezone, err)
+	}
+
+	identityFields, err := getIdentityFields(cfg)
+	if err != nil {
+		l.Error("invalid identity fields", zap.Error(err))
+		return nil, err
+	}
+
+	cb, err := connector.New(ctx, providerConfigs, &connector.Options{
+		UseExternalID:      cfg.GetBool(useExternalID),
+		Transport:          getTransportConfig(cfg),
+		CacheTTL:           time.Duration(cfg.GetInt(cacheTTL)) * time.Second,
+		CacheMaxEntries:    cfg.GetInt(cacheSize),
+		SessionStore:       runTimeOpts.SessionStore,
+		ProgressInterval:   time.Duration(cfg.GetInt(progressEvery)) * time.Second,
+		ReportPath:         cfg.GetString(syncReport),
+		MaxAccountFailures: cfg.GetInt(maxFailures),
+		PlaceholderUsers:   cfg.GetBool(placeholders),
+		Scope: connector.Scope{
+			IncludeGroups:   cfg.GetStringSlice(includeGroups),
+			ExcludeGroups:   cfg.GetStringSlice(excludeGroups),
+			IncludePaths:    cfg.GetStringSlice(includePaths),
+			ExcludePaths:    cfg.GetStringSlice(excludePaths),
+			IncludeProducts: cfg.GetStringSlice(includeProds),
+			ExcludeProducts: cfg.GetStringSlice(excludeProds),
+		},
+		TicketStore:        cfg.GetString(ticketStore),
+		TicketWebhookURL:   cfg.GetString(ticketWebhook),
+		TicketWebhookToken: cfg.GetString(ticketToken),
+		EventsDir:          cfg.GetString(eventsDir),
+		IdentityProfile:    cfg.GetBool(identityProf),
+		IdentityFields:     identityFields,
+		KYCVerified:        cfg.GetBool(kycVerified),
+		BusinessResources:  cfg.GetBool(businesses),
+		DryRun:             cfg.GetBool(dryRun),
+		Usage: connector.UsageOptions{
+			Window:   time.Duration(cfg.GetInt(usageWindow)) * 24 * time.Hour,
+			MaxPages: cfg.GetInt(usageMaxPages),
+			MaxCalls: cfg.GetInt(usageMaxCalls),
+			Location: usageLocation,
+		},
 	})
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
 	}
 
-	c, err := connectorbuilder.NewConnector(ctx, cb)
+	var opts []connectorbuilder.Opt
+	if cfg.GetBool(field.TicketingField.FieldName) {
+		opts = append(opts, connectorbuilder.WithTicketingEnabled())
+	}
+
+	c, err := connector.NewConnectorServer(ctx, cb, opts...)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
@@ -96,3 +458,88 @@ func getConnector(ctx context.Context, cfg *viper.Viper) (types.ConnectorServer,
 
 	return c, nil
 }
+
+// getTransportConfig returns the configuration of the connections to Galileo-FT.
+func getTransportConfig(cfg *viper.Viper) galileo.TransportConfig {
+	return galileo.TransportConfig{
+		ClientCert:          cfg.GetString(clientCert),
+		ClientKey:           cfg.GetString(clientKey),
+		CABundle:            cfg.GetString(caBundle),
+		HTTPProxy:           cfg.GetString(httpProxy),
+		HTTPSProxy:          cfg.GetString(httpsProxy),
+		ProxyUsername:       cfg.GetString(proxyUsername),
+		ProxyPassword:       cfg.GetString(proxyPassword),
+		MaxIdleConnsPerHost: cfg.GetInt(maxIdleConns),
+		IdleConnTimeout:     time.Duration(cfg.GetInt(idleTimeout)) * time.Second,
+	}
+}
+
+// getIdentityFields returns the names of the identity verification fields given as field=name pairs.
+func getIdentityFields(cfg *viper.Viper) (galileo.IdentityFields, error) {
+	var rv galileo.IdentityFields
+	for _, pair := range cfg.GetStringSlice(identityNames) {
+		key, name, ok := strings.Cut(pair, "=")
+		key, name = strings.TrimSpace(key), strings.TrimSpace(name)
+		if !ok || name == "" {
+			return rv, fmt.Errorf("invalid --%s %q, expected field=name", identityNames, pair)
+		}
+
+		switch key {
+		case galileo.DefaultIdentityFields.CIPStatus:
+			rv.CIPStatus = name
+		case galileo.DefaultIdentityFields.KYCResult:
+			rv.KYCResult = name
+		case galileo.DefaultIdentityFields.OFACMatch:
+			rv.OFACMatch = name
+		default:
+			return rv, fmt.Errorf("invalid --%s %q, unknown field %s", identityNames, pair, key)
+		}
+	}
+
+	return rv, nil
+}
+
+// getProviderConfigs returns the configuration of every Galileo program to sync:
+// the unlabeled provider configured by the individual flags, followed by the labeled providers of the providers list.
+func getProviderConfigs(cfg *viper.Viper) ([]*galileo.Config, error) {
+	var rv []*galileo.Config
+
+	if cfg.GetString(providerID) != "" {
+		rv = append(rv, &galileo.Config{
+			Environment: cfg.GetString(environment),
+			Hostname:    cfg.GetString(hostname),
+			BaseURL:     cfg.GetString(baseURL),
+			APILogin:    cfg.GetString(apiLogin),
+			APITransKey: cfg.GetString(apiTransKey),
+			ProviderID:  cfg.GetString(providerID),
+		})
+	}
+
+	raw := cfg.GetString(providers)
+	if raw == "" {
+		return rv, nil
+	}
+
+	var labeled []*galileo.Config
+	err := json.Unmarshal([]byte(raw), &labeled)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", providers, err)
+	}
+
+	for _, p := range labeled {
+		if p.Label == "" {
+			return nil, fmt.Errorf("invalid %s: every provider must have a label", providers)
+		}
+
+		if p.ProviderID == "" || p.APILogin == "" || p.APITransKey == "" {
+			return nil, fmt.Errorf("invalid %s: provider %s is missing credentials", providers, p.Label)
+		}
+
+		// providers without their own endpoint use the environment of the connector
+		if p.Environment == "" && p.BaseURL == "" && p.Hostname == "" {
+			p.Environment = cfg.GetString(environment)
+		}
+	}
+
+	return append(rv, labeled...), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+)
+
+const (
+	redacted     = "***"
+	revealedTail = 4
+)
+
+// redactCustomer returns a copy of the customer without its personal data.
+// Identifiers and the verification state are kept, as they are needed to troubleshoot a sync.
+func redactCustomer(customer *galileo.Customer) *galileo.Customer {
+	rv := *customer
+
+	rv.FirstName = redactName(rv.FirstName)
+	rv.MiddleName = redactName(rv.MiddleName)
+	rv.LastName = redactName(rv.LastName)
+	rv.Email = redactEmail(rv.Email)
+	rv.HomePhone = redactTail(rv.HomePhone)
+	rv.MobilePhone = redactTail(rv.MobilePhone)
+	rv.Address1 = redactAll(rv.Address1)
+	rv.Address2 = redactAll(rv.Address2)
+	rv.City = redactAll(rv.City)
+	rv.PostalCode = redactAll(rv.PostalCode)
+
+	return &rv
+}
+
+// redactName keeps the initial of every word of a name, e.g. J*** D***.
+func redactName(value string) string {
+	words := strings.Fields(value)
+	for i, w := range words {
+		words[i] = string([]rune(w)[0]) + redacted
+	}
+
+	return strings.Join(words, " ")
+}
+
+// redactEmail keeps the first character of the mailbox and the domain, e.g. j***@example.com.
+func redactEmail(value string) string {
+	at := strings.LastIndex(value, "@")
+	if at <= 0 {
+		return redactAll(value)
+	}
+
+	return string([]rune(value)[0]) + redacted + value[at:]
+}
+
+// redactTail keeps the last characters of a number, e.g. ***1234.
+func redactTail(value string) string {
+	if len(value) <= revealedTail {
+		return redactAll(value)
+	}
+
+	return redacted + value[len(value)-revealedTail:]
+}
+
+func redactAll(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return redacted
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/events"
+	"github.com/conductorone/baton-sdk/pkg/field"
+	"github.com/conductorone/baton-sdk/pkg/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	listenAddress   = "listen-address"
+	webhookSecret   = "webhook-secret"
+	signatureHeader = "signature-header"
+	eventTypes      = "event-types"
+	eventRetention  = "event-retention"
+	tlsCertFile     = "tls-cert-file"
+	tlsKeyFile      = "tls-key-file"
+
+	pruneInterval = time.Hour
+)
+
+// webhookFields are the options of the webhook command.
+var webhookFields = []field.SchemaField{
+	field.StringField(
+		listenAddress,
+		field.WithDescription("Address the webhook listens on."),
+		field.WithDefaultValue(":8080"),
+	),
+	field.StringField(
+		webhookSecret,
+		field.WithDescription("Secret shared with Galileo-FT to sign the events."),
+		field.WithIsSecret(true),
+	),
+	field.StringField(
+		signatureHeader,
+		field.WithDescription("Header carrying the signature of the events, as set up with the Galileo-FT event subscription."),
+		field.WithDefaultValue(events.DefaultSignatureHeader),
+	),
+	field.StringSliceField(
+		eventTypes,
+		field.WithDescription("Galileo-FT event types of the event types of the connector, as type=galileo-type pairs, for events not sent with the type names of the connector: "+
+			strings.Join([]string{events.TypeAccountStatusChange, events.TypeCardActivation, events.TypeGroupChange, events.TypeAccountGroupChange}, ", ")+"."),
+	),
+	field.StringField(
+		eventsDir,
+		field.WithDescription("Directory the received events are queued in."),
+	),
+	field.IntField(
+		eventRetention,
+		field.WithDescription("Hours the received events are kept in the queue."),
+		field.WithDefaultValue(168),
+	),
+	field.StringField(
+		tlsCertFile,
+		field.WithDescription("Certificate file of the webhook, served over HTTPS when set with --"+tlsKeyFile+"."),
+	),
+	field.StringField(
+		tlsKeyFile,
+		field.WithDescription("Private key file of the certificate of the webhook."),
+		field.WithIsSecret(true),
+	),
+}
+
+// webhookCommand returns the command receiving the events pushed by Galileo into the event queue read by the connector.
+func webhookCommand(ctx context.Context, v *viper.Viper) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "webhook",
+		Short: "Receive Galileo-FT events for the event feed of the connector",
+		Long: "Run an HTTP endpoint receiving the events pushed by Galileo-FT. Events signed with the webhook secret in the " +
+			"--signature-header header are queued in --events-dir, from which the connector feeds them to Baton.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := v.BindPFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			return runWebhook(ctx, v)
+		},
+	}
+
+	addFieldFlags(cmd, webhookFields)
+
+	return cmd
+}
+
+// addFieldFlags adds a flag per field to the command, named and described as the fields of the connector.
+func addFieldFlags(cmd *cobra.Command, fields []field.SchemaField) {
+	for _, f := range fields {
+		usage := fmt.Sprintf("%s ($BATON_%s)", f.GetDescription(), strings.ToUpper(strings.ReplaceAll(f.GetName(), "-", "_")))
+
+		switch f.Variant {
+		case field.IntVariant:
+			value, _ := f.DefaultValue.(int)
+			cmd.Flags().Int(f.GetName(), value, usage)
+		case field.StringSliceVariant:
+			value, _ := f.DefaultValue.([]string)
+			cmd.Flags().StringSlice(f.GetName(), value, usage)
+		default:
+			value, _ := f.DefaultValue.(string)
+			cmd.Flags().String(f.GetName(), value, usage)
+		}
+	}
+}
+
+func runWebhook(ctx context.Context, v *viper.Viper) error {
+	ctx, err := logging.Init(ctx, logging.WithLogFormat(v.GetString("log-format")), logging.WithLogLevel(v.GetString("log-level")))
+	if err != nil {
+		return err
+	}
+
+	l := ctxzap.Extract(ctx)
+
+	dir := v.GetString(eventsDir)
+	if dir == "" {
+		return fmt.Errorf("--%s is required", eventsDir)
+	}
+
+	retention := time.Duration(v.GetInt(eventRetention)) * time.Hour
+	if retention <= 0 {
+		return fmt.Errorf("--%s must be positive", eventRetention)
+	}
+
+	queue, err := events.NewQueue(dir)
+	if err != nil {
+		return err
+	}
+
+	types, err := events.ParseEventTypes(v.GetStringSlice(eventTypes))
+	if err != nil {
+		return fmt.Errorf("invalid --%s: %w", eventTypes, err)
+	}
+
+	receiver, err := events.NewReceiver(
+		v.GetString(webhookSecret),
+		queue,
+		events.WithSignatureHeader(v.GetString(signatureHeader)),
+		events.WithEventTypes(types),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid --%s: %w", webhookSecret, err)
+	}
+
+	certFile, keyFile := v.GetString(tlsCertFile), v.GetString(tlsKeyFile)
+	if (certFile == "") != (keyFile == "") {
+		return fmt.Errorf("--%s and --%s must be set together", tlsCertFile, tlsKeyFile)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:              v.GetString(listenAddress),
+		Handler:           receiver,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go pruneEvents(ctx, queue, retention)
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			l.Error("galileo-ft-connector: failed to shut down webhook", zap.Error(err))
+		}
+	}()
+
+	l.Info(
+		"galileo-ft-connector: webhook listening",
+		zap.String("address", server.Addr),
+		zap.String("events_dir", dir),
+		zap.Bool("tls", certFile != ""),
+	)
+
+	if certFile != "" {
+		server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		err = server.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// pruneEvents removes the events older than the retention from the queue, until the context is done.
+func pruneEvents(ctx context.Context, queue *events.Queue, retention time.Duration) {
+	l := ctxzap.Extract(ctx)
+
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := queue.Prune(time.Now().Add(-retention))
+		if err != nil {
+			l.Warn("galileo-ft-connector: failed to prune event queue", zap.Error(err))
+		} else if removed > 0 {
+			l.Info("galileo-ft-connector: pruned event queue", zap.Int("removed", removed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	config "github.com/conductorone/baton-sdk/pb/c1/config/v1"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Cardholder actions, invoked on the user of the account holding the card.
+const (
+	actionReissueCard   = "reissue_card"
+	actionResetPIN      = "reset_pin"
+	actionReplaceCard   = "replace_lost_stolen_card"
+	actionArgResource   = "resource_id"
+	actionArgUser       = "user"
+	actionArgReason     = "reason"
+	actionOutAccount    = "account"
+	actionOutCardID     = "card_id"
+	actionOutDryRun     = "dry_run"
+	replaceReasonLost   = "lost"
+	replaceReasonStolen = "stolen"
+)
+
+var resourceArgument = config.Field_builder{
+	Name:            actionArgResource,
+	DisplayName:     "User",
+	Description:     "The user of the account holding the card.",
+	IsRequired:      true,
+	ResourceIdField: config.ResourceIdField_builder{}.Build(),
+}.Build()
+
+// userArgument is the synced user of resourceArgument, whose profile holds the account PRN
+// when users are identified by the external ID of their customer.
+var userArgument = config.Field_builder{
+	Name:          actionArgUser,
+	DisplayName:   "Synced user",
+	Description:   "The synced user given as resource_id, whose profile holds the account PRN. Required when users are identified by external ID.",
+	ResourceField: config.ResourceField_builder{}.Build(),
+}.Build()
+
+// dryRunReturnType reports that the action was validated and logged without changing anything in Galileo.
+var dryRunReturnType = config.Field_builder{
+	Name:        actionOutDryRun,
+	DisplayName: "Dry run",
+	Description: "Whether the action was only validated and logged, without sending the change to Galileo.",
+	BoolField:   config.BoolField_builder{}.Build(),
+}.Build()
+
+// cardReturnTypes are the outputs of every card action, card numbers are never returned.
+var cardReturnTypes = []*config.Field{
+	config.Field_builder{
+		Name:        "success",
+		DisplayName: "Success",
+		BoolField:   config.BoolField_builder{}.Build(),
+	}.Build(),
+	config.Field_builder{
+		Name:        actionOutAccount,
+		DisplayName: "Account",
+		Description: "The account PRN holding the card after the operation.",
+		StringField: config.StringField_builder{}.Build(),
+	}.Build(),
+	config.Field_builder{
+		Name:        actionOutCardID,
+		DisplayName: "Card ID",
+		Description: "The Galileo card ID of the card after the operation, not returned in a dry run.",
+		StringField: config.StringField_builder{}.Build(),
+	}.Build(),
+	dryRunReturnType,
+}
+
+// ResourceActions registers the cardholder operations on users, and the update of their profile.
+func (u *userBuilder) ResourceActions(ctx context.Context, registry actions.ActionRegistry) error {
+	err := registry.Register(ctx, v2.BatonActionSchema_builder{
+		Name:        actionReissueCard,
+		DisplayName: "Reissue card",
+		Description: "Reissue the card of the account, e.g. when it is damaged or about to expire.",
+		Arguments:   []*config.Field{resourceArgument, userArgument},
+		ReturnTypes: cardReturnTypes,
+	}.Build(), u.cardAction(func(*structpb.Struct) (galileo.CardOperation, error) {
+		return galileo.CardReissue, nil
+	}))
+	if err != nil {
+		return err
+	}
+
+	err = registry.Register(ctx, v2.BatonActionSchema_builder{
+		Name:        actionResetPIN,
+		DisplayName: "Reset PIN",
+		Description: "Reset the PIN of the card of the account, so the cardholder sets a new one.",
+		Arguments:   []*config.Field{resourceArgument, userArgument},
+		ReturnTypes: cardReturnTypes,
+	}.Build(), u.cardAction(func(*structpb.Struct) (galileo.CardOperation, error) {
+		return galileo.CardResetPIN, nil
+	}))
+	if err != nil {
+		return err
+	}
+
+	err = registry.Register(ctx, v2.BatonActionSchema_builder{
+		Name:        actionReplaceCard,
+		DisplayName: "Replace lost or stolen card",
+		Description: "Mark the card of the account as lost or stolen and issue a replacement card.",
+		Arguments: []*config.Field{
+			resourceArgument,
+			userArgument,
+			config.Field_builder{
+				Name:        actionArgReason,
+				DisplayName: "Reason",
+				Description: "Whether the card was lost or stolen.",
+				IsRequired:  true,
+				StringField: config.StringField_builder{
+					Options: []*config.StringFieldOption{
+						config.StringFieldOption_builder{Name: replaceReasonLost, Value: replaceReasonLost, DisplayName: "Lost"}.Build(),
+						config.StringFieldOption_builder{Name: replaceReasonStolen, Value: replaceReasonStolen, DisplayName: "Stolen"}.Build(),
+					},
+				}.Build(),
+			}.Build(),
+		},
+		ReturnTypes: cardReturnTypes,
+	}.Build(), u.cardAction(func(args *structpb.Struct) (galileo.CardOperation, error) {
+		reason, err := actions.RequireStringArg(args, actionArgReason)
+		if err != nil {
+			return "", status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		switch reason {
+		case replaceReasonLost:
+			return galileo.CardLostReplace, nil
+		case replaceReasonStolen:
+			return galileo.CardStolenReplace, nil
+		default:
+			return "", status.Errorf(codes.InvalidArgument, "galileo-ft-connector: invalid reason %q, expected %s or %s", reason, replaceReasonLost, replaceReasonStolen)
+		}
+	}))
+	if err != nil {
+		return err
+	}
+
+	return u.registerUpdateProfile(ctx, registry)
+}
+
+// cardAction returns the handler of an action applying a card operation to the account of the user given in the arguments.
+func (u *userBuilder) cardAction(operation func(args *structpb.Struct) (galileo.CardOperation, error)) actions.ActionHandler {
+	return func(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+		l := ctxzap.Extract(ctx)
+
+		// the operation is decided on the current state in Galileo, not on responses cached during the sync
+		ctx = galileo.WithNoCache(ctx)
+
+		p, accID, err := u.actionAccount(ctx, args)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		op, err := operation(args)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// nothing is sent to Galileo in a dry run, so the account is checked instead
+		dryRun := p.client.DryRun()
+		if dryRun {
+			err = checkActionAccount(ctx, p, accID)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		card, err := p.client.ModifyCardStatus(ctx, accID, op)
+		if err != nil {
+			return nil, nil, fmt.Errorf("galileo-ft-connector: failed to modify card status of account %s: %w", p.resourceID(accID), err)
+		}
+
+		if dryRun {
+			return actions.NewReturnValues(true,
+				actions.NewStringReturnField(actionOutAccount, p.resourceID(accID)),
+				actions.NewBoolReturnField(actionOutDryRun, true),
+			), nil, nil
+		}
+
+		// replacement cards may be issued on a new account
+		account, cardID := accID, card.CardID
+		if card.NewAccountNo != "" {
+			account = card.NewAccountNo
+		}
+
+		if card.NewCardID != "" {
+			cardID = card.NewCardID
+		}
+
+		l.Info(
+			"galileo-ft-connector: card status modified",
+			zap.String("account", p.resourceID(accID)),
+			zap.String("operation", string(op)),
+			zap.String("new_account", p.resourceID(account)),
+		)
+
+		return actions.NewReturnValues(true,
+			actions.NewStringReturnField(actionOutAccount, p.resourceID(account)),
+			actions.NewStringReturnField(actionOutCardID, cardID),
+			actions.NewBoolReturnField(actionOutDryRun, false),
+		), nil, nil
+	}
+}
+
+// actionAccount returns the provider and the account PRN of the user given in the action arguments.
+// When users are identified by external ID, the PRN is read from the profile of the synced user argument.
+// Without it, the user ID is accepted if it is the PRN of an account whose customer has no external ID,
+// as such users are identified by their PRN.
+func (u *userBuilder) actionAccount(ctx context.Context, args *structpb.Struct) (*provider, string, error) {
+	resourceID, err := actions.RequireResourceIDArg(args, actionArgResource)
+	if err != nil {
+		return nil, "", status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if resourceID.ResourceType != userResourceType.Id {
+		return nil, "", status.Errorf(codes.InvalidArgument, "galileo-ft-connector: card actions apply to users, got %s", resourceID.ResourceType)
+	}
+
+	p, id, err := u.providers.parseResourceID(resourceID.Resource)
+	if err != nil {
+		return nil, "", status.Errorf(codes.InvalidArgument, "galileo-ft-connector: failed to parse user ID: %s", err)
+	}
+
+	if !u.useExternalID {
+		return p, id, nil
+	}
+
+	if user := syncedUser(args, resourceID); user != nil {
+		if prn, ok := accountPRN(user); ok {
+			return p, prn, nil
+		}
+	}
+
+	customer, err := p.client.GetCustomer(ctx, id)
+	if err != nil && !isAccountError(err) {
+		return nil, "", fmt.Errorf("galileo-ft-connector: failed to get customer of account %s: %w", resourceID.Resource, err)
+	}
+
+	if err != nil || customer == nil || customer.ExternalID != "" {
+		return nil, "", status.Errorf(
+			codes.InvalidArgument,
+			"galileo-ft-connector: the account PRN of user %s is unknown, pass the synced user in the %s argument",
+			resourceID.Resource,
+			actionArgUser,
+		)
+	}
+
+	return p, id, nil
+}
+
+// checkActionAccount fails with NotFound if the account is unknown to Galileo or has no customer.
+func checkActionAccount(ctx context.Context, p *provider, accID string) error {
+	customer, err := p.client.GetCustomer(ctx, accID)
+	if err == nil && customer == nil {
+		err = errNoCustomer
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if isAccountError(err) {
+		return status.Errorf(codes.NotFound, "galileo-ft-connector: account %s not found: %v", p.resourceID(accID), err)
+	}
+
+	return fmt.Errorf("galileo-ft-connector: failed to get customer of account %s: %w", p.resourceID(accID), err)
+}
+
+// syncedUser returns the synced user argument if it is the user given as resource_id, or nil.
+func syncedUser(args *structpb.Struct, resourceID *v2.ResourceId) *v2.Resource {
+	user, ok := actions.GetResourceFieldArg(args, actionArgUser)
+	if !ok || user.GetId().GetResource() != resourceID.GetResource() {
+		return nil
+	}
+
+	return user
+}
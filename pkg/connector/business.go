@@ -0,0 +1,171 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// businessResource creates the business resource of a root group, named after its trade name or legal name.
+func businessResource(p *provider, group *galileo.Group) (*v2.Resource, error) {
+	name := group.Business
+	if name == "" {
+		name = group.LegalName
+	}
+
+	if name == "" {
+		name = group.Name
+	}
+
+	businessProfile := map[string]interface{}{
+		"group-id":          group.ID,
+		"group-name":        group.Name,
+		"external-id":       group.ExternalID,
+		"legal-name":        group.LegalName,
+		"doing-business-as": group.Business,
+		"contact-email":     group.ContactEmail,
+		"contact-name":      group.ContactName,
+	}
+
+	if p.label != "" {
+		businessProfile["provider"] = p.label
+	}
+
+	options := []rs.ResourceOption{
+		rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: userResourceType.Id}),
+	}
+
+	if group.ExternalID != "" {
+		options = append(options, rs.WithExternalID(&v2.ExternalId{Id: group.ExternalID}))
+	}
+
+	resource, err := rs.NewAppResource(
+		name,
+		businessResourceType,
+		p.resourceID(group.ID),
+		[]rs.AppTraitOption{
+			rs.WithAppProfile(businessProfile),
+		},
+		options...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// businessBuilder syncs the businesses, the root groups of the providers.
+// Memberships and primary contacts are managed as for any group, so it relies on the group builder.
+type businessBuilder struct {
+	*groupBuilder
+}
+
+func (b *businessBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return businessResourceType
+}
+
+// List returns the businesses of the providers, a page of root groups per call.
+// Root groups out of scope are skipped as by the group builder, which lists the departments under the businesses.
+func (b *businessBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	ctx, done := b.progress.startPhase(ctx, phaseBusinessList)
+	defer done()
+
+	bag, err := b.parseProvidersPageToken(pToken.Token)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to parse page token: %w", err)
+	}
+
+	p, err := b.providers.get(bag.ResourceID())
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to get provider: %w", err)
+	}
+
+	cursor, err := parseGroupsCursor(bag.PageToken())
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to parse page token: %w", err)
+	}
+
+	pgVars := galileo.NewPaginationVars(cursor.Page, ResourcesPageSize)
+	groups, totalNumOfPages, err := p.client.ListRootGroups(ctx, pgVars)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to list root groups: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, rootGroup := range groups {
+		if _, whole := b.scope.rootGroup(p, &rootGroup); !whole { // #nosec G601
+			continue
+		}
+
+		br, err := businessResource(p, &rootGroup) // #nosec G601
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to create business resource: %w", err)
+		}
+
+		rv = append(rv, br)
+	}
+
+	b.progress.addGroups(len(rv), 0)
+
+	cursor.Pages = totalNumOfPages
+	cursor.nextPage()
+
+	next, err := cursor.token()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to prepare next page token: %w", err)
+	}
+
+	err = bag.Next(next)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to prepare next page token: %w", err)
+	}
+
+	nextPage, err := bag.Marshal()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to prepare next page token: %w", err)
+	}
+
+	return rv, nextPage, nil, nil
+}
+
+func (b *businessBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	var rv []*v2.Entitlement
+
+	assignmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(userResourceType),
+		ent.WithDisplayName(fmt.Sprintf("Business %s %s", resource.DisplayName, GroupMembership)),
+		ent.WithDescription(fmt.Sprintf("Employee of business %s", resource.DisplayName)),
+	}
+
+	rv = append(rv, ent.NewAssignmentEntitlement(resource, GroupMembership, assignmentOptions...))
+
+	contactOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(userResourceType),
+		ent.WithDisplayName(fmt.Sprintf("Business %s %s", resource.DisplayName, GroupPrimaryContact)),
+		ent.WithDescription(fmt.Sprintf("Primary contact of business %s", resource.DisplayName)),
+	}
+
+	rv = append(rv, ent.NewPermissionEntitlement(resource, GroupPrimaryContact, contactOptions...))
+
+	return rv, "", nil, nil
+}
+
+func newBusinessBuilder(
+	providers *providerSet,
+	cache *galileo.ResponseCache,
+	state *syncState,
+	progress *syncProgress,
+	scope *scopeFilter,
+	useExternalID bool,
+) *businessBuilder {
+	return &businessBuilder{
+		groupBuilder: newGroupBuilder(providers, cache, state, progress, scope, true, useExternalID),
+	}
+}
@@ -2,27 +2,95 @@ package connector
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"time"
 
+	"github.com/conductorone/baton-galileo-ft/pkg/events"
 	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
-	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+// Options holds the connector settings shared by all providers.
+type Options struct {
+	// UseExternalID identifies users by the Galileo external ID of their customer instead of the account PRN.
+	UseExternalID bool
+	// Transport holds the TLS and proxy settings used to reach the Galileo API.
+	Transport galileo.TransportConfig
+	// CacheTTL is how long Galileo responses are cached within a sync, zero disables the cache.
+	CacheTTL time.Duration
+	// CacheMaxEntries bounds the number of responses cached in memory.
+	CacheMaxEntries int
+	// SessionStore is the SDK session store of the syncs, if enabled. It holds the cached responses of a sync,
+	// so they are kept across the invocations of the connector within the sync. The memory is used otherwise.
+	SessionStore sessions.SessionStore
+	// ProgressInterval is how often the sync progress is logged, zero disables progress logs.
+	ProgressInterval time.Duration
+	// ReportPath is the file the JSON summary of every sync is written to, if set.
+	ReportPath string
+	// MaxAccountFailures is the number of accounts per sync that can fail with an account specific error
+	// (e.g. a closed account) and be skipped, before the sync fails. Zero fails the sync on the first failed account.
+	MaxAccountFailures int
+	// PlaceholderUsers emits a user with minimal data for the skipped accounts.
+	PlaceholderUsers bool
+	// Scope selects the groups and products synced, everything is synced by default.
+	Scope Scope
+	// TicketStore is the directory program change tickets are written to.
+	TicketStore string
+	// TicketWebhookURL is the webhook program change tickets are posted to, instead of a local directory.
+	TicketWebhookURL string
+	// TicketWebhookToken authenticates the requests to the ticket webhook.
+	TicketWebhookToken string
+	// IdentityProfile adds the identity verification state of the customer (CIP status, KYC result, OFAC match) to the user profile.
+	IdentityProfile bool
+	// IdentityFields names the profile fields of the identity verification state, the defaults are used for empty names.
+	IdentityFields galileo.IdentityFields
+	// KYCVerified syncs the kyc-verified entitlement, granted to the users whose customer passed the identity verification.
+	KYCVerified bool
+	// BusinessResources syncs root groups as business resources, parents of their department groups.
+	// It changes the resource type of root groups, so it is off by default.
+	BusinessResources bool
+	// DryRun validates grants, revokes and actions and logs the Galileo requests they would send, without sending them.
+	DryRun bool
+	// Usage enables the summary of the recent card activity of every account on its user.
+	Usage UsageOptions
+	// EventsDir is the queue of the Galileo events received by the webhook, fed to the event feed of the connector.
+	EventsDir string
+}
+
 type Galileo struct {
-	client *galileo.Client
+	providers *providerSet
+	cache     *galileo.ResponseCache
+	state     *syncState
+	progress  *syncProgress
+	scope     *scopeFilter
+	tickets   ticketStore
+	events    *eventFeed
+	opts      *Options
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (g *Galileo) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
-	return []connectorbuilder.ResourceSyncer{
-		newUserBuilder(g.client),
-		newGroupBuilder(g.client),
+	rv := []connectorbuilder.ResourceSyncer{
+		newUserBuilder(g.providers, g.state, g.progress, g.scope, g.opts),
+		newGroupBuilder(g.providers, g.cache, g.state, g.progress, g.scope, g.opts.BusinessResources, g.opts.UseExternalID),
+	}
+
+	if g.opts.BusinessResources {
+		rv = append(rv, newBusinessBuilder(g.providers, g.cache, g.state, g.progress, g.scope, g.opts.UseExternalID))
 	}
+
+	if g.opts.KYCVerified {
+		rv = append(rv, newVerificationBuilder(g.providers))
+	}
+
+	return rv
 }
 
 // Asset takes an input AssetRef and attempts to fetch it using the connector's authenticated http client
@@ -33,36 +101,131 @@ func (g *Galileo) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.Rea
 
 // Metadata returns metadata about the connector.
 func (g *Galileo) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
+	// the applied scope is recorded, so a partial sync can be told apart from a full one
+	profile, err := structpb.NewStruct(map[string]interface{}{
+		"scope": g.scope.describe(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to create metadata profile: %w", err)
+	}
+
 	return &v2.ConnectorMetadata{
 		DisplayName: "Galileo-FT",
 		Description: "Connector syncing Galileo-FT accounts and groups to Baton",
+		Profile:     profile,
 	}, nil
 }
 
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
 func (g *Galileo) Validate(ctx context.Context) (annotations.Annotations, error) {
-	err := g.client.Ping(ctx)
-	if err != nil {
-		return nil, status.Error(codes.Unauthenticated, "galileo-ft-connector: failed to validate credentials")
+	for _, p := range g.providers.list {
+		target := fmt.Sprintf("the %s environment", p.client.Environment())
+		if p.label != "" {
+			target = fmt.Sprintf("provider %s in the %s environment", p.label, p.client.Environment())
+		}
+
+		err := p.client.Ping(ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "galileo-ft-connector: failed to reach %s: %v", target, err)
+		}
+
+		// Credentials are issued per environment and program, so an authenticated request listing the root groups
+		// of the program confirms they belong to the selected environment and can read the groups synced.
+		_, _, err = p.client.ListRootGroups(ctx, galileo.NewPaginationVars(1, 1))
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "galileo-ft-connector: failed to validate credentials of %s: %v", target, err)
+		}
 	}
 
 	return nil, nil
 }
 
-// New returns a new instance of the connector.
-func New(ctx context.Context, cfg *galileo.Config) (*Galileo, error) {
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, nil))
+// New returns a new instance of the connector syncing the Galileo programs of the given provider configurations.
+func New(ctx context.Context, cfgs []*galileo.Config, opts *Options) (*Galileo, error) {
+	if len(cfgs) == 0 {
+		return nil, fmt.Errorf("galileo-ft-connector: no provider configured")
+	}
+
+	httpClient, err := galileo.NewHTTPClient(ctx, &opts.Transport)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("galileo-ft-connector: failed to create http client: %w", err)
 	}
 
-	client, err := galileo.NewClient(httpClient, cfg)
+	scope := newScopeFilter(opts.Scope)
+	progress := newSyncProgress(opts.ProgressInterval, opts.ReportPath, scope.describe())
+	clientOpts := []galileo.ClientOption{
+		galileo.WithCallObserver(progress.observeCall),
+		galileo.WithIdentityFields(opts.IdentityFields),
+	}
+	if opts.DryRun {
+		clientOpts = append(clientOpts, galileo.WithDryRun())
+	}
+
+	// a single cache is shared by all providers, responses are keyed by host and provider
+	var cache *galileo.ResponseCache
+	if opts.CacheTTL > 0 {
+		store := galileo.NewMemoryCacheStore(opts.CacheMaxEntries)
+		if opts.SessionStore != nil {
+			store = galileo.NewSessionCacheStore(opts.SessionStore, sessionResponsesPrefix, store)
+		}
+
+		cache = galileo.NewResponseCache(store, opts.CacheTTL)
+		clientOpts = append(clientOpts, galileo.WithCache(cache))
+	}
+
+	list := make([]*provider, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		client, err := galileo.NewClient(httpClient, cfg, clientOpts...)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, &provider{
+			label:  cfg.Label,
+			client: client,
+		})
+	}
+
+	providers, err := newProviderSet(list)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("galileo-ft-connector: invalid providers: %w", err)
+	}
+
+	var tickets ticketStore
+	switch {
+	case opts.TicketWebhookURL != "":
+		tickets, err = newWebhookTicketStore(ctx, opts.TicketWebhookURL, opts.TicketWebhookToken)
+	case opts.TicketStore != "":
+		tickets, err = newFileTicketStore(opts.TicketStore)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: invalid ticket store: %w", err)
+	}
+
+	var feed *eventFeed
+	if opts.EventsDir != "" {
+		queue, err := events.NewQueue(opts.EventsDir)
+		if err != nil {
+			return nil, fmt.Errorf("galileo-ft-connector: invalid event queue: %w", err)
+		}
+
+		feed = &eventFeed{
+			providers:     providers,
+			queue:         queue,
+			useExternalID: opts.UseExternalID,
+			businesses:    opts.BusinessResources,
+		}
 	}
 
 	return &Galileo{
-		client: client,
+		providers: providers,
+		cache:     cache,
+		state:     newSyncState(opts.SessionStore),
+		progress:  progress,
+		scope:     scope,
+		tickets:   tickets,
+		events:    feed,
+		opts:      opts,
 	}, nil
 }
@@ -0,0 +1,374 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+)
+
+// standInAccount is an account of the Galileo stand-in.
+type standInAccount struct {
+	customer galileo.Customer
+	product  string
+	group    string
+	// related are the PRNs of the secondary accounts related to the account.
+	related []string
+	// status is the Galileo status every request on the account is rejected with, if not zero.
+	status uint
+}
+
+// galileoStandIn is a stand-in of the Galileo API, holding groups and accounts in memory.
+type galileoStandIn struct {
+	mu       sync.Mutex
+	groups   map[string]*galileo.Group
+	accounts map[string]*standInAccount
+	// calls counts the requests by endpoint.
+	calls map[string]int
+}
+
+func newGalileoStandIn() *galileoStandIn {
+	return &galileoStandIn{
+		groups:   make(map[string]*galileo.Group),
+		accounts: make(map[string]*standInAccount),
+		calls:    make(map[string]int),
+	}
+}
+
+func (s *galileoStandIn) addGroup(id, parentID, name string) *galileo.Group {
+	group := &galileo.Group{ID: id, ParentGroupID: parentID, Name: name}
+	s.groups[id] = group
+
+	return group
+}
+
+func (s *galileoStandIn) addAccount(prn, groupID, email string) *standInAccount {
+	acc := &standInAccount{
+		customer: galileo.Customer{FirstName: "Customer", LastName: prn, Email: email},
+		group:    groupID,
+	}
+	s.accounts[prn] = acc
+
+	return acc
+}
+
+func (s *galileoStandIn) callCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[path]
+}
+
+func (s *galileoStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[r.URL.Path]++
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	accID := r.PostForm.Get("accountNo")
+	groupID := r.PostForm.Get("groupId")
+
+	acc, ok := s.accounts[accID]
+	if accID != "" && (!ok || acc.status != 0) {
+		code := galileo.StatusAccountNotFound
+		if ok {
+			code = acc.status
+		}
+
+		s.reply(w, code, nil)
+		return
+	}
+
+	switch r.URL.Path {
+	case galileo.AccountOverviewEndpoint:
+		s.reply(w, 0, map[string]interface{}{"profile": acc.customer, "product_id": acc.product})
+	case galileo.RelatedAccountsEndpoint:
+		children := make([]galileo.Account, 0, len(acc.related))
+		for _, prn := range acc.related {
+			children = append(children, galileo.Account{ID: prn, ProdID: s.accounts[prn].product})
+		}
+
+		s.reply(w, 0, galileo.RelatedAccountsResponse{Children: children})
+	case galileo.GroupsToAccountsEndpoint:
+		if accID != "" {
+			relationships := []galileo.GroupToAccounts{}
+			if acc.group != "" {
+				relationships = append(relationships, galileo.GroupToAccounts{GroupID: acc.group, AccountIDs: []string{accID}})
+			}
+
+			s.reply(w, 0, relationships)
+			return
+		}
+
+		members := s.members(groupID)
+		if len(members) == 0 {
+			s.reply(w, 0, []galileo.GroupToAccounts{})
+			return
+		}
+
+		s.reply(w, 0, []galileo.GroupToAccounts{{GroupID: groupID, AccountIDs: members}})
+	case galileo.RootGroupsEndpoint:
+		roots := []galileo.Group{}
+		for _, id := range s.groupIDs() {
+			if s.groups[id].ParentGroupID == "" {
+				roots = append(roots, *s.groups[id])
+			}
+		}
+
+		s.replyJSON(w, map[string]interface{}{"status_code": 0, "response_data": roots, "page": 1, "number_of_pages": 1})
+	case galileo.GroupHierarchyEndpoint:
+		s.reply(w, 0, s.hierarchy(groupID))
+	case galileo.GroupInfoEndpoint:
+		groups := []galileo.Group{}
+		for _, id := range r.PostForm["groupIds"] {
+			if group, ok := s.groups[id]; ok {
+				groups = append(groups, *group)
+			}
+		}
+
+		s.reply(w, 0, groups)
+	case galileo.ModifyGroupEndpoint:
+		group := s.groups[groupID]
+		group.ContactName = r.PostForm.Get("primaryContactName")
+		group.ContactEmail = r.PostForm.Get("primaryContactEmail")
+		s.reply(w, 0, nil)
+	case galileo.AddAccountToGroupEndpoint:
+		for _, prn := range r.PostForm["accountNos"] {
+			s.accounts[prn].group = groupID
+		}
+
+		s.reply(w, 0, nil)
+	case galileo.RemoveAccountFromGroupEndpoint:
+		for _, prn := range r.PostForm["accountNos"] {
+			s.accounts[prn].group = ""
+		}
+
+		s.reply(w, 0, nil)
+	case galileo.AddRelatedAccountEndpoint:
+		primary := s.accounts[r.PostForm.Get("primaryAccountNo")]
+		primary.related = append(primary.related, accID)
+		s.reply(w, 0, nil)
+	case galileo.RemoveRelatedAccountEndpoint:
+		primary := s.accounts[r.PostForm.Get("primaryAccountNo")]
+		related := primary.related[:0]
+		for _, prn := range primary.related {
+			if prn != accID {
+				related = append(related, prn)
+			}
+		}
+		primary.related = related
+		s.reply(w, 0, nil)
+	default:
+		s.reply(w, 0, nil)
+	}
+}
+
+// reply writes a Galileo response, which has a 200 status even when Galileo rejects the request.
+func (s *galileoStandIn) reply(w http.ResponseWriter, code uint, data interface{}) {
+	response := map[string]interface{}{"status_code": code, "status": "Success"}
+	if code != 0 {
+		response["status"] = "Rejected"
+	}
+
+	if data != nil {
+		response["response_data"] = data
+	}
+
+	s.replyJSON(w, response)
+}
+
+func (s *galileoStandIn) replyJSON(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (s *galileoStandIn) groupIDs() []string {
+	ids := make([]string, 0, len(s.groups))
+	for id := range s.groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+func (s *galileoStandIn) members(groupID string) []string {
+	var members []string
+	for prn, acc := range s.accounts {
+		if acc.group == groupID {
+			members = append(members, prn)
+		}
+	}
+	sort.Strings(members)
+
+	return members
+}
+
+func (s *galileoStandIn) hierarchy(parentID string) []galileo.GroupHierarchy {
+	rv := []galileo.GroupHierarchy{}
+	for _, id := range s.groupIDs() {
+		if group := s.groups[id]; group.ParentGroupID == parentID {
+			rv = append(rv, galileo.GroupHierarchy{ID: id, Name: group.Name, Children: s.hierarchy(id)})
+		}
+	}
+
+	return rv
+}
+
+// sessionStandIn is a stand-in of the SDK session store, keeping the values of every sync in memory.
+type sessionStandIn struct {
+	sessions.SessionStore
+
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newSessionStandIn() *sessionStandIn {
+	return &sessionStandIn{values: make(map[string][]byte)}
+}
+
+func (s *sessionStandIn) key(ctx context.Context, key string, opts []sessions.SessionStoreOption) string {
+	bag := &sessions.SessionStoreBag{}
+	for _, opt := range opts {
+		_ = opt(ctx, bag)
+	}
+
+	return bag.SyncID + "/" + bag.Prefix + "/" + key
+}
+
+func (s *sessionStandIn) Get(ctx context.Context, key string, opt ...sessions.SessionStoreOption) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[s.key(ctx, key, opt)]
+	return value, ok, nil
+}
+
+func (s *sessionStandIn) Set(ctx context.Context, key string, value []byte, opt ...sessions.SessionStoreOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[s.key(ctx, key, opt)] = value
+	return nil
+}
+
+func (s *sessionStandIn) Clear(ctx context.Context, opt ...sessions.SessionStoreOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix := s.key(ctx, "", opt)
+	for key := range s.values {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.values, key)
+		}
+	}
+
+	return nil
+}
+
+// newStandInConnector returns a connector syncing the Galileo stand-in with the given options.
+func newStandInConnector(t *testing.T, standIn *galileoStandIn, opts *Options) *Galileo {
+	t.Helper()
+
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	g, err := New(context.Background(), []*galileo.Config{{
+		Environment: string(galileo.EnvironmentCustom),
+		BaseURL:     server.URL,
+		APILogin:    "login",
+		APITransKey: "key",
+		ProviderID:  "provider",
+	}}, opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	return g
+}
+
+// resourceSyncer returns the syncer of the given resource type of the connector.
+func resourceSyncer(t *testing.T, g *Galileo, resourceType *v2.ResourceType) connectorbuilder.ResourceSyncer {
+	t.Helper()
+
+	for _, syncer := range g.ResourceSyncers(context.Background()) {
+		if syncer.ResourceType(context.Background()).Id == resourceType.Id {
+			return syncer
+		}
+	}
+
+	t.Fatalf("no syncer of resource type %s", resourceType.Id)
+	return nil
+}
+
+// resourceProvisioner returns the syncer of the given resource type of the connector, which provisions its grants.
+func resourceProvisioner(t *testing.T, g *Galileo, resourceType *v2.ResourceType) connectorbuilder.ResourceProvisioner {
+	t.Helper()
+
+	provisioner, ok := resourceSyncer(t, g, resourceType).(connectorbuilder.ResourceProvisioner)
+	if !ok {
+		t.Fatalf("syncer of resource type %s does not provision grants", resourceType.Id)
+	}
+
+	return provisioner
+}
+
+// listAll lists all the pages of resources of the syncer under the parent.
+func listAll(t *testing.T, syncer connectorbuilder.ResourceSyncer, parentID *v2.ResourceId) []*v2.Resource {
+	t.Helper()
+
+	var rv []*v2.Resource
+	token := ""
+	for {
+		resources, next, _, err := syncer.List(context.Background(), parentID, &pagination.Token{Token: token})
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+
+		rv = append(rv, resources...)
+		if next == "" {
+			return rv
+		}
+
+		token = next
+	}
+}
+
+// findResource returns the resource with the given ID, or nil.
+func findResource(resources []*v2.Resource, id string) *v2.Resource {
+	for _, r := range resources {
+		if r.Id.Resource == id {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// grantsOf returns the grants of the entitlement with the given slug, as principal resource IDs.
+func grantsOf(grants []*v2.Grant, slug string) []string {
+	var rv []string
+	for _, g := range grants {
+		if strings.HasSuffix(g.Entitlement.Id, ":"+slug) {
+			rv = append(rv, g.Principal.Id.Resource)
+		}
+	}
+	sort.Strings(rv)
+
+	return rv
+}
@@ -0,0 +1,122 @@
+package connector
+
+import (
+	"encoding/json"
+)
+
+// groupsPageToken is the page token of the group listing: the page states of the providers
+// and the progress of the sync so far, restored when a sync resumes from the token.
+type groupsPageToken struct {
+	Providers string      `json:"providers"`
+	Progress  *syncReport `json:"progress,omitempty"`
+}
+
+func parseGroupsPageToken(token string) (*groupsPageToken, error) {
+	rv := &groupsPageToken{}
+	if token == "" {
+		return rv, nil
+	}
+
+	err := json.Unmarshal([]byte(token), rv)
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (t *groupsPageToken) marshal() (string, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// groupsCursor is the position of the group listing of a provider, kept in its page state.
+type groupsCursor struct {
+	// Page is the page of root groups being listed, starting at 1.
+	Page uint `json:"page"`
+	// Pages is the number of pages of root groups, as of the last page listed.
+	Pages uint `json:"pages,omitempty"`
+	// Roots are the IDs of the root groups of the page in scope, whose descendants are being listed.
+	// It is empty until the page itself is listed.
+	Roots []string `json:"roots,omitempty"`
+	// Names are the names of the root groups of Roots, the first element of the paths of their descendants.
+	Names []string `json:"names,omitempty"`
+	// Included are the root groups of Roots that are in scope with all their descendants.
+	Included []string `json:"included,omitempty"`
+	// Root is the index in Roots of the root group whose descendants are being listed.
+	Root int `json:"root,omitempty"`
+	// Chunk is the index of the chunk of descendants to list next.
+	Chunk int `json:"chunk,omitempty"`
+}
+
+func parseGroupsCursor(token string) (*groupsCursor, error) {
+	cursor := &groupsCursor{Page: 1}
+	if token == "" {
+		return cursor, nil
+	}
+
+	err := json.Unmarshal([]byte(token), cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	return cursor, nil
+}
+
+// nextRoot moves the cursor to the descendants of the next root group of the page, or to the next page.
+func (c *groupsCursor) nextRoot() {
+	c.Root++
+	c.Chunk = 0
+
+	if c.Root >= len(c.Roots) {
+		c.nextPage()
+	}
+}
+
+// nextPage moves the cursor to the next page of root groups.
+func (c *groupsCursor) nextPage() {
+	c.Page++
+	c.Roots = nil
+	c.Names = nil
+	c.Included = nil
+	c.Root = 0
+	c.Chunk = 0
+}
+
+// rootName returns the name of the root group whose descendants are being listed.
+func (c *groupsCursor) rootName() string {
+	if c.Root < len(c.Names) {
+		return c.Names[c.Root]
+	}
+
+	return ""
+}
+
+// included returns whether the root group is in scope with all its descendants.
+func (c *groupsCursor) included(rootID string) bool {
+	for _, id := range c.Included {
+		if id == rootID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// token returns the page token of the cursor, or an empty token once all pages are listed.
+func (c *groupsCursor) token() (string, error) {
+	if c.Page > c.Pages {
+		return "", nil
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
@@ -0,0 +1,169 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/events"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	eventFeedID       = "galileo_events"
+	eventFeedPageSize = 100
+)
+
+// eventFeed feeds the Galileo events queued by the webhook as Baton resource change events,
+// so the changed users and groups are synced without waiting for the next full sync.
+type eventFeed struct {
+	providers     *providerSet
+	queue         *events.Queue
+	useExternalID bool
+	// businesses is set when root groups are synced as business resources.
+	businesses bool
+}
+
+// EventFeeds returns the Galileo event feed, when an event queue is configured.
+func (g *Galileo) EventFeeds(ctx context.Context) []connectorbuilder.EventFeed {
+	if g.events == nil {
+		return nil
+	}
+
+	return []connectorbuilder.EventFeed{g.events}
+}
+
+func (f *eventFeed) EventFeedMetadata(ctx context.Context) *v2.EventFeedMetadata {
+	return v2.EventFeedMetadata_builder{
+		Id:                  eventFeedID,
+		SupportedEventTypes: []v2.EventType{v2.EventType_EVENT_TYPE_RESOURCE_CHANGE},
+	}.Build()
+}
+
+// ListEvents returns the queued events following the cursor, the cursor being the last event returned.
+// Events that occurred before the earliest event requested are skipped.
+func (f *eventFeed) ListEvents(
+	ctx context.Context,
+	earliestEvent *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	size, cursor := eventFeedPageSize, ""
+	if pToken != nil {
+		cursor = pToken.Cursor
+		if pToken.Size > 0 {
+			size = pToken.Size
+		}
+	}
+
+	entries, hasMore, err := f.queue.List(cursor, size)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("galileo-ft-connector: failed to list events: %w", err)
+	}
+
+	var rv []*v2.Event
+	for _, entry := range entries {
+		cursor = entry.Cursor
+		if earliestEvent != nil && entry.Event.OccurredAt().Before(earliestEvent.AsTime()) {
+			continue
+		}
+
+		converted, err := f.convert(ctx, entry.Event)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		rv = append(rv, converted...)
+	}
+
+	return rv, &pagination.StreamState{Cursor: cursor, HasMore: hasMore}, nil, nil
+}
+
+// convert returns the Baton events of a Galileo event, events of unknown types or providers are skipped.
+func (f *eventFeed) convert(ctx context.Context, e *events.Event) ([]*v2.Event, error) {
+	l := ctxzap.Extract(ctx).With(zap.String("event_id", e.ID), zap.String("event_type", e.Type))
+
+	p, ok := f.provider(e.ProviderID)
+	if !ok {
+		l.Warn("galileo-ft-connector: skipping event of an unknown provider", zap.String("provider_id", e.ProviderID))
+		return nil, nil
+	}
+
+	var groupID *v2.ResourceId
+	if e.GroupID != "" {
+		groupType, err := syncedGroupType(ctx, p, e.GroupID, f.businesses)
+		if err != nil {
+			return nil, err
+		}
+
+		groupID = v2.ResourceId_builder{ResourceType: groupType.Id, Resource: p.resourceID(e.GroupID)}.Build()
+	}
+
+	var changed []*v2.ResourceChangeEvent
+	switch e.Type {
+	case events.TypeAccountStatusChange, events.TypeCardActivation, events.TypeAccountGroupChange:
+		if e.AccountNo == "" {
+			l.Warn("galileo-ft-connector: skipping event without an account")
+			return nil, nil
+		}
+
+		// transient errors fail the page so it is listed again, failed accounts are identified by their PRN as their placeholder
+		userID, err := accountResourceID(ctx, p, e.AccountNo, f.useExternalID)
+		if err != nil {
+			return nil, err
+		}
+
+		changed = append(changed, v2.ResourceChangeEvent_builder{
+			ResourceId: userID,
+		}.Build())
+
+		// the members of the group changed too
+		if e.Type == events.TypeAccountGroupChange && groupID != nil {
+			changed = append(changed, v2.ResourceChangeEvent_builder{ResourceId: groupID}.Build())
+		}
+	case events.TypeGroupChange:
+		if groupID == nil {
+			l.Warn("galileo-ft-connector: skipping event without a group")
+			return nil, nil
+		}
+
+		changed = append(changed, v2.ResourceChangeEvent_builder{ResourceId: groupID}.Build())
+	default:
+		l.Debug("galileo-ft-connector: skipping event of an unsupported type")
+		return nil, nil
+	}
+
+	rv := make([]*v2.Event, 0, len(changed))
+	for i, c := range changed {
+		rv = append(rv, v2.Event_builder{
+			Id:                  fmt.Sprintf("%s-%d", e.ID, i),
+			OccurredAt:          timestamppb.New(e.OccurredAt()),
+			ResourceChangeEvent: c,
+		}.Build())
+	}
+
+	return rv, nil
+}
+
+// provider returns the provider of an event, events without a provider ID belong to the only provider.
+func (f *eventFeed) provider(providerID string) (*provider, bool) {
+	if providerID == "" {
+		if len(f.providers.list) == 1 {
+			return f.providers.list[0], true
+		}
+
+		return nil, false
+	}
+
+	for _, p := range f.providers.list {
+		if p.client.ProviderID() == providerID {
+			return p, true
+		}
+	}
+
+	return nil, false
+}
@@ -3,6 +3,8 @@ package connector
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -13,37 +15,74 @@ import (
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
-	RootGroupsType  = "root"
-	GroupMembership = "member"
+	RootGroupsType      = "root"
+	GroupMembership     = "member"
+	GroupPrimaryContact = "primary-contact"
 )
 
 type groupBuilder struct {
-	client       *galileo.Client
+	providers    *providerSet
 	resourceType *v2.ResourceType
+	cache        *galileo.ResponseCache
+	state        *syncState
+	progress     *syncProgress
+	scope        *scopeFilter
+	// businesses leaves the root groups to the business builder, and parents the department groups to their businesses.
+	businesses bool
+	// useExternalID identifies the users granted access by the external ID of their customer, as the user builder does.
+	useExternalID bool
+
+	mu sync.Mutex
+	// descendants holds the descendants in scope of the root groups being listed, by root group resource ID,
+	// so the hierarchy of a root group is fetched once per sync rather than for every chunk.
+	descendants map[string]*rootDescendants
+}
+
+// rootDescendants are the descendants in scope of a root group.
+type rootDescendants struct {
+	ids     []string
+	inScope map[string]bool
+	// groups are the infos of the descendants, fetched with the hierarchy when groups are selected by ID,
+	// since only getGroupsInfo returns their external IDs. They are nil otherwise.
+	groups   map[string]galileo.Group
+	failures map[string]error
 }
 
 func (g *groupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return groupResourceType
 }
 
-func groupResource(group *galileo.Group) (*v2.Resource, error) {
+// groupResource creates the resource of a group, under the resource of its parent group of the given resource type.
+func groupResource(p *provider, group *galileo.Group, parentType *v2.ResourceType) (*v2.Resource, error) {
 	groupProfile := map[string]interface{}{
 		"group-id":      group.ID,
+		"external-id":   group.ExternalID,
 		"legal-name":    group.LegalName,
 		"business":      group.Business,
 		"contact-email": group.ContactEmail,
 		"contact-name":  group.ContactName,
 	}
 
+	if p.label != "" {
+		groupProfile["provider"] = p.label
+	}
+
 	options := []rs.ResourceOption{
 		rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: userResourceType.Id}),
 	}
 
+	if group.ExternalID != "" {
+		options = append(options, rs.WithExternalID(&v2.ExternalId{Id: group.ExternalID}))
+	}
+
 	if group.ParentGroupID != "" {
-		parentID, err := rs.NewResourceID(groupResourceType, group.ParentGroupID)
+		parentID, err := rs.NewResourceID(parentType, p.resourceID(group.ParentGroupID))
 		if err != nil {
 			return nil, err
 		}
@@ -54,7 +93,7 @@ func groupResource(group *galileo.Group) (*v2.Resource, error) {
 	resource, err := rs.NewGroupResource(
 		group.Name,
 		groupResourceType,
-		group.ID,
+		p.resourceID(group.ID),
 		[]rs.GroupTraitOption{
 			rs.WithGroupProfile(groupProfile),
 		},
@@ -73,62 +112,344 @@ func groupResource(group *galileo.Group) (*v2.Resource, error) {
 // - An account can belong to only one group at a time.
 // - The maximum number of levels below a root group is five, making six levels total.
 // More information about groups and their hierarchy: https://docs.galileo-ft.com/pro/docs/creating-a-corporate-hierarchy
+//
+// Root groups of every configured provider are listed one provider after another,
+// the label of the provider being listed is kept in the page state.
+// Every call lists either a page of root groups or a chunk of the descendants of one root group,
+// so calls stay bounded and a restarted sync resumes from the last cursor.
+// The page token also keeps the progress of the sync, restored when a sync resumes from it.
 func (g *groupBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: RootGroupsType})
+	// Root groups are listed first in every sync, before any of the accounts under them.
+	if pToken.Token == "" {
+		g.progress.Reset()
+		g.resetDescendants()
+
+		if g.cache != nil {
+			err := g.cache.Reset(ctx)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to reset response cache: %w", err)
+			}
+		}
+
+		err := g.state.Reset(ctx)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to reset sync state: %w", err)
+		}
+	}
+
+	token, err := parseGroupsPageToken(pToken.Token)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to parse page token: %w", err)
 	}
 
-	pgVars := galileo.NewPaginationVars(page, ResourcesPageSize)
-	groups, totalNumOfPages, err := g.client.ListRootGroups(ctx, pgVars)
+	g.progress.resume(token.Progress)
+
+	ctx, done := g.progress.startPhase(ctx, phaseGroupList)
+	defer done()
+
+	bag, err := g.parseProvidersPageToken(token.Providers)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to parse page token: %w", err)
+	}
+
+	p, err := g.providers.get(bag.ResourceID())
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to get provider: %w", err)
+	}
+
+	cursor, err := parseGroupsCursor(bag.PageToken())
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to parse page token: %w", err)
+	}
+
+	var rv []*v2.Resource
+	if cursor.Roots == nil {
+		rv, err = g.listRootGroups(ctx, p, cursor)
+	} else {
+		rv, err = g.listChildrenGroups(ctx, p, cursor)
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	next, err := cursor.token()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to prepare next page token: %w", err)
+	}
+
+	// Once the provider is fully listed, its page state is popped and the next provider follows.
+	err = bag.Next(next)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to prepare next page token: %w", err)
+	}
+
+	nextProviders, err := bag.Marshal()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to prepare next page token: %w", err)
+	}
+
+	if nextProviders == "" {
+		return rv, "", nil, nil
+	}
+
+	// the progress of this page is included, the phase is only done once the page is returned
+	progress, err := g.progress.snapshot()
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to list root groups: %w", err)
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to prepare next page token: %w", err)
+	}
+
+	nextPage, err := (&groupsPageToken{Providers: nextProviders, Progress: progress}).marshal()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to prepare next page token: %w", err)
+	}
+
+	return rv, nextPage, nil, nil
+}
+
+// listRootGroups lists the root groups of the page of the cursor and keeps their IDs in the cursor,
+// so that their descendants are listed in the next calls. Businesses are only kept in the cursor.
+func (g *groupBuilder) listRootGroups(ctx context.Context, p *provider, cursor *groupsCursor) ([]*v2.Resource, error) {
+	pgVars := galileo.NewPaginationVars(cursor.Page, ResourcesPageSize)
+	groups, totalNumOfPages, err := p.client.ListRootGroups(ctx, pgVars)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to list root groups: %w", err)
 	}
 
 	var rv []*v2.Resource
+	var included []string
+	roots := make([]string, 0, len(groups))
+	names := make([]string, 0, len(groups))
 	for _, rootGroup := range groups {
-		// Create a resource for each root group.
-		gr, err := groupResource(&rootGroup) // #nosec G601
+		listed, whole := g.scope.rootGroup(p, &rootGroup) // #nosec G601
+		if !listed {
+			continue
+		}
+
+		roots = append(roots, rootGroup.ID)
+		names = append(names, rootGroup.Name)
+
+		// root groups out of scope are not synced, but their descendants may be
+		if !whole {
+			continue
+		}
+
+		included = append(included, rootGroup.ID)
+
+		// root groups are listed as businesses by the business builder
+		if g.businesses {
+			continue
+		}
+
+		gr, err := groupResource(p, &rootGroup, groupResourceType) // #nosec G601
 		if err != nil {
-			return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to create group resource: %w", err)
+			return nil, fmt.Errorf("galileo-ft-connector: failed to create group resource: %w", err)
 		}
 
 		rv = append(rv, gr)
+	}
 
-		// Check if have any children.
-		childrenGroupIDs, err := g.client.ListChildrenGroups(ctx, rootGroup.ID)
-		if err != nil {
-			return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to list children groups: %w", err)
+	g.progress.addGroups(len(rv), 0)
+
+	cursor.Pages = totalNumOfPages
+	cursor.Roots = roots
+	cursor.Names = names
+	cursor.Included = included
+	if len(roots) == 0 {
+		cursor.nextPage()
+	}
+
+	return rv, nil
+}
+
+// listChildrenGroups lists the chunk of the descendants of the root group of the cursor.
+// Descendants are listed in chunks of the maximum number of groups of a single getGroupsInfo request.
+// Descendants whose parent is out of scope are listed without parent.
+func (g *groupBuilder) listChildrenGroups(ctx context.Context, p *provider, cursor *groupsCursor) ([]*v2.Resource, error) {
+	l := ctxzap.Extract(ctx)
+
+	rootID := cursor.Roots[cursor.Root]
+	rootIncluded := cursor.included(rootID)
+
+	descendants, err := g.rootDescendants(ctx, p, rootID, cursor.rootName(), rootIncluded)
+	if err != nil {
+		return nil, err
+	}
+
+	childrenGroupIDs := descendants.ids
+
+	// a resumed sync fetches the hierarchy again, it may have changed since the previous chunk
+	// and the remaining descendants are listed anyway
+	start := cursor.Chunk * galileo.GroupsInfoMaxIDs
+	if start >= len(childrenGroupIDs) {
+		g.forgetDescendants(p, rootID)
+		cursor.nextRoot()
+		return nil, nil
+	}
+
+	end := min(start+galileo.GroupsInfoMaxIDs, len(childrenGroupIDs))
+	if end < len(childrenGroupIDs) {
+		cursor.Chunk++
+	} else {
+		g.forgetDescendants(p, rootID)
+		cursor.nextRoot()
+	}
+
+	// Fetch information about children groups, groups rejected by Galileo are skipped, any other failure fails the call.
+	children, failures, err := descendants.info(ctx, p, childrenGroupIDs[start:end])
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to get children groups info: %w", err)
+	}
+
+	for id, err := range failures {
+		l.Warn(
+			"galileo-ft-connector: skipping child group",
+			zap.String("root_group_id", rootID),
+			zap.String("group_id", id),
+			zap.Error(err),
+		)
+	}
+
+	var rv []*v2.Resource
+	for _, group := range children {
+		parentInScope := descendants.inScope[group.ParentGroupID] || (group.ParentGroupID == rootID && rootIncluded)
+		if !parentInScope {
+			group.ParentGroupID = ""
 		}
 
-		if len(childrenGroupIDs) == 0 {
-			continue
+		// departments directly under a business are parented to the business resource
+		parentType := groupResourceType
+		if g.businesses && group.ParentGroupID == rootID {
+			parentType = businessResourceType
 		}
 
-		// Fetch information about children groups
-		children, err := g.client.GetGroupsInfo(ctx, childrenGroupIDs)
+		cgr, err := groupResource(p, &group, parentType) // #nosec G601
 		if err != nil {
-			return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to get children groups info: %w", err)
+			return nil, fmt.Errorf("galileo-ft-connector: failed to create group resource: %w", err)
 		}
 
-		// Create a resource for each child group.
-		for _, group := range children {
-			cgr, err := groupResource(&group) // #nosec G601
-			if err != nil {
-				return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to create group resource: %w", err)
-			}
+		rv = append(rv, cgr)
+	}
+
+	g.progress.addGroups(0, len(children))
+
+	return rv, nil
+}
+
+// rootDescendants returns the descendants in scope of the root group,
+// fetching its hierarchy on the first chunk of the root group listed by this process.
+func (g *groupBuilder) rootDescendants(ctx context.Context, p *provider, rootID, rootName string, included bool) (*rootDescendants, error) {
+	key := p.resourceID(rootID)
+
+	g.mu.Lock()
+	rv, ok := g.descendants[key]
+	g.mu.Unlock()
 
-			rv = append(rv, cgr)
+	if ok {
+		return rv, nil
+	}
+
+	hierarchy, err := p.client.GetGroupHierarchy(ctx, rootID)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to list children groups: %w", err)
+	}
+
+	rv = &rootDescendants{}
+
+	var externalIDs map[string]string
+	if g.scope.selectsGroups() {
+		groups, failures, err := p.client.GetGroupsInfo(ctx, hierarchyIDs(hierarchy))
+		if err != nil {
+			return nil, fmt.Errorf("galileo-ft-connector: failed to get children groups info: %w", err)
+		}
+
+		rv.groups = make(map[string]galileo.Group, len(groups))
+		rv.failures = failures
+		externalIDs = make(map[string]string, len(groups))
+		for _, group := range groups {
+			rv.groups[group.ID] = group
+			externalIDs[group.ID] = group.ExternalID
+		}
+	}
+
+	rv.ids = g.scope.descendants(p, hierarchy, rootName, included, externalIDs)
+	rv.inScope = make(map[string]bool, len(rv.ids))
+	for _, id := range rv.ids {
+		rv.inScope[id] = true
+	}
+
+	g.mu.Lock()
+	g.descendants[key] = rv
+	g.mu.Unlock()
+
+	return rv, nil
+}
+
+// info returns the infos of the given descendants, from the infos fetched with the hierarchy if any.
+func (d *rootDescendants) info(ctx context.Context, p *provider, ids []string) ([]galileo.Group, map[string]error, error) {
+	if d.groups == nil {
+		return p.client.GetGroupsInfo(ctx, ids)
+	}
+
+	var groups []galileo.Group
+	failures := make(map[string]error)
+	for _, id := range ids {
+		if group, ok := d.groups[id]; ok {
+			groups = append(groups, group)
+		} else if err, ok := d.failures[id]; ok {
+			failures[id] = err
 		}
 	}
 
-	next := prepareNextToken(page, totalNumOfPages)
-	nextPage, err := bag.NextToken(next)
+	return groups, failures, nil
+}
+
+// hierarchyIDs returns the IDs of all the groups of the hierarchy.
+func hierarchyIDs(hierarchy []galileo.GroupHierarchy) []string {
+	var ids []string
+	for _, g := range hierarchy {
+		ids = append(ids, g.ID)
+		ids = append(ids, hierarchyIDs(g.Children)...)
+	}
+
+	return ids
+}
+
+// forgetDescendants drops the descendants of a root group once they are all listed.
+func (g *groupBuilder) forgetDescendants(p *provider, rootID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.descendants, p.resourceID(rootID))
+}
+
+// resetDescendants drops the descendants of all root groups. It is called at the start of every sync.
+func (g *groupBuilder) resetDescendants() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.descendants = make(map[string]*rootDescendants)
+}
+
+// parseProvidersPageToken parses the page token of the root groups,
+// pushing a page state for every provider on the first call.
+func (g *groupBuilder) parseProvidersPageToken(token string) (*pagination.Bag, error) {
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(token)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to prepare next page token: %w", err)
+		return nil, err
 	}
 
-	return rv, nextPage, nil, nil
+	if bag.Current() == nil {
+		// push in reverse, so the providers are listed in the configured order
+		for i := len(g.providers.list) - 1; i >= 0; i-- {
+			bag.Push(pagination.PageState{
+				ResourceTypeID: RootGroupsType,
+				ResourceID:     g.providers.list[i].label,
+			})
+		}
+	}
+
+	return bag, nil
 }
 
 func (g *groupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
@@ -142,29 +463,146 @@ func (g *groupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _
 
 	rv = append(rv, ent.NewAssignmentEntitlement(resource, GroupMembership, assignmentOptions...))
 
+	contactOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(userResourceType),
+		ent.WithDisplayName(fmt.Sprintf("Group %s %s", resource.DisplayName, GroupPrimaryContact)),
+		ent.WithDescription(fmt.Sprintf("Primary contact of group %s", resource.DisplayName)),
+	}
+
+	rv = append(rv, ent.NewPermissionEntitlement(resource, GroupPrimaryContact, contactOptions...))
+
 	return rv, "", nil, nil
 }
 
 func (g *groupBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	ctx, done := g.progress.startPhase(ctx, phaseGroupGrants)
+	defer done()
+
 	var rv []*v2.Grant
 
-	group, err := g.client.ListGroupMembers(ctx, resource.Id.Resource)
+	p, groupID, err := g.providers.parseResourceID(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to parse group ID: %w", err)
+	}
+
+	group, err := p.client.ListGroupMembers(ctx, groupID)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to list group members: %w", err)
 	}
 
+	// members of products out of scope are not synced, nor granted
+	members := make([]string, 0, len(group.AccountIDs))
 	for _, accID := range group.AccountIDs {
-		accID, err := rs.NewResourceID(userResourceType, accID)
+		if g.scope.member(ctx, p, accID) {
+			members = append(members, accID)
+		}
+	}
+
+	for _, accID := range members {
+		accID, err := accountResourceID(ctx, p, accID, g.useExternalID)
 		if err != nil {
-			return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to create user resource ID: %w", err)
+			return nil, "", nil, err
 		}
 
 		rv = append(rv, grant.NewGrant(resource, GroupMembership, accID))
 	}
 
+	contactGrant, err := g.primaryContactGrant(ctx, p, resource, members)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if contactGrant != nil {
+		rv = append(rv, contactGrant)
+	}
+
 	return rv, "", nil, nil
 }
 
+// primaryContactGrant resolves the primary contact of the group to one of its members by email.
+// The customers of the members were fetched when the users were listed, so they are usually served by the response cache.
+func (g *groupBuilder) primaryContactGrant(ctx context.Context, p *provider, resource *v2.Resource, members []string) (*v2.Grant, error) {
+	l := ctxzap.Extract(ctx)
+
+	profile, err := groupProfile(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	email, ok := rs.GetProfileStringValue(profile, "contact-email")
+	if !ok || email == "" {
+		return nil, nil
+	}
+
+	for _, accID := range members {
+		customer, err := p.client.GetCustomer(ctx, accID)
+		if err != nil {
+			// members whose customer cannot be fetched were tolerated when listing users
+			if isAccountError(err) {
+				continue
+			}
+
+			return nil, fmt.Errorf("galileo-ft-connector: failed to get customer of account %s: %w", p.resourceID(accID), err)
+		}
+
+		if customer == nil || !strings.EqualFold(customer.Email, email) {
+			continue
+		}
+
+		principalID, err := accountResourceID(ctx, p, accID, g.useExternalID)
+		if err != nil {
+			return nil, err
+		}
+
+		return grant.NewGrant(resource, GroupPrimaryContact, principalID), nil
+	}
+
+	l.Debug(
+		"galileo-ft-connector: primary contact of group does not match any member",
+		zap.String("group_id", resource.Id.Resource),
+	)
+
+	return nil, nil
+}
+
+// syncedGroupType returns the resource type a group is synced as, root groups being businesses when business resources are synced.
+func syncedGroupType(ctx context.Context, p *provider, groupID string, businesses bool) (*v2.ResourceType, error) {
+	if !businesses {
+		return groupResourceType, nil
+	}
+
+	// transient errors fail the call so it is made again, a group Galileo rejects is reported as a department
+	groups, _, err := p.client.GetGroupsInfo(ctx, []string{groupID})
+	if err != nil && !isAccountError(err) {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to get group %s: %w", p.resourceID(groupID), err)
+	}
+
+	if len(groups) == 1 && groups[0].ParentGroupID == "" {
+		return businessResourceType, nil
+	}
+
+	return groupResourceType, nil
+}
+
+// groupProfile returns the profile of a group or business resource.
+func groupProfile(resource *v2.Resource) (*structpb.Struct, error) {
+	if resource.Id.ResourceType == businessResourceType.Id {
+		appTrait, err := rs.GetAppTrait(resource)
+		if err != nil {
+			return nil, fmt.Errorf("galileo-ft-connector: failed to get app trait: %w", err)
+		}
+
+		return appTrait.GetProfile(), nil
+	}
+
+	groupTrait, err := rs.GetGroupTrait(resource)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to get group trait: %w", err)
+	}
+
+	return groupTrait.GetProfile(), nil
+}
+
 func (g *groupBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 
@@ -178,12 +616,26 @@ func (g *groupBuilder) Grant(ctx context.Context, principal *v2.Resource, entitl
 		return nil, fmt.Errorf("galileo-ft-connector: only users can be granted group membership")
 	}
 
-	err := g.client.AddAccountToGroup(ctx, principal.Id.Resource, entitlement.Resource.Id.Resource)
+	p, accID, groupID, err := g.parseGrant(principal, entitlement)
 	if err != nil {
-		return nil, fmt.Errorf("galileo-ft-connector: failed to grant group membership: %w", err)
+		return nil, err
 	}
 
-	return nil, nil
+	// the change is decided on the current state in Galileo, not on responses cached during the sync
+	ctx = galileo.WithNoCache(ctx)
+
+	// the primary contact is not a move of the account, its membership is checked when granting it
+	if entitlementSlug(entitlement) == GroupPrimaryContact {
+		return g.grantPrimaryContact(ctx, p, accID, groupID)
+	}
+
+	membership := galileo.Membership{AccountID: accID, GroupID: groupID}
+	state, err := g.checkMembership(ctx, p, membership)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.grantMembership(ctx, p, membership, state)
 }
 
 func (g *groupBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
@@ -202,17 +654,156 @@ func (g *groupBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations
 		return nil, fmt.Errorf("galileo-ft-connector: only users can have group membership revoked")
 	}
 
-	err := g.client.RemoveAccountFromGroup(ctx, principal.Id.Resource, entitlement.Resource.Id.Resource)
+	p, accID, groupID, err := g.parseGrant(principal, entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	// the change is decided on the current state in Galileo, not on responses cached during the sync
+	ctx = galileo.WithNoCache(ctx)
+
+	// the primary contact is cleared even if the account has left the group since it was granted
+	if entitlementSlug(entitlement) == GroupPrimaryContact {
+		return g.revokePrimaryContact(ctx, p, accID, groupID)
+	}
+
+	membership := galileo.Membership{AccountID: accID, GroupID: groupID}
+	state, err := g.checkMembership(ctx, p, membership)
 	if err != nil {
-		return nil, fmt.Errorf("galileo-ft-connector: failed to revoke group membership: %w", err)
+		return nil, err
+	}
+
+	return g.revokeMembership(ctx, p, membership, state)
+}
+
+// parseGrant returns the provider, the account PRN of the principal and the Galileo ID of the group of the entitlement.
+// Accounts can only be granted access to groups of the same provider.
+func (g *groupBuilder) parseGrant(principal *v2.Resource, entitlement *v2.Entitlement) (*provider, string, string, error) {
+	p, accID, err := g.providers.parseAccount(principal)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("galileo-ft-connector: failed to parse principal: %w", err)
+	}
+
+	groupProvider, groupID, err := g.providers.parseResourceID(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("galileo-ft-connector: failed to parse group ID: %w", err)
+	}
+
+	if p != groupProvider {
+		return nil, "", "", fmt.Errorf("galileo-ft-connector: account %s and group %s belong to different providers", accID, groupID)
+	}
+
+	return p, accID, groupID, nil
+}
+
+// grantPrimaryContact sets the customer of the account as the primary contact of the group, unless it already is.
+func (g *groupBuilder) grantPrimaryContact(ctx context.Context, p *provider, accID, groupID string) (annotations.Annotations, error) {
+	customer, err := p.client.GetCustomer(ctx, accID)
+	if err == nil && customer == nil {
+		err = errNoCustomer
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to get customer: %w", err)
+	}
+
+	if customer.Email == "" {
+		return nil, fmt.Errorf("galileo-ft-connector: account %s has no email to set as primary contact", accID)
+	}
+
+	// the primary contact is synced among the members in scope of the group, so only they can be granted it
+	currentGroup, err := p.client.GetAccountGroup(ctx, accID)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to get group of account %s: %w", p.resourceID(accID), err)
+	}
+
+	if currentGroup != groupID || !g.scope.member(ctx, p, accID) {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"galileo-ft-connector: account %s is not a member of group %s and cannot be its primary contact",
+			p.resourceID(accID),
+			p.resourceID(groupID),
+		)
+	}
+
+	contactEmail, err := g.primaryContactEmail(ctx, p, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(contactEmail, customer.Email) {
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	contactName := fmt.Sprintf("%s %s", customer.FirstName, customer.LastName)
+	err = p.client.SetGroupPrimaryContact(ctx, groupID, contactName, customer.Email)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to set group primary contact: %w", err)
+	}
+
+	return nil, nil
+}
+
+// revokePrimaryContact clears the primary contact of the group, if it is still the customer of the account.
+func (g *groupBuilder) revokePrimaryContact(ctx context.Context, p *provider, accID, groupID string) (annotations.Annotations, error) {
+	customer, err := p.client.GetCustomer(ctx, accID)
+	if err == nil && customer == nil {
+		err = errNoCustomer
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to get customer: %w", err)
+	}
+
+	contactEmail, err := g.primaryContactEmail(ctx, p, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if customer.Email == "" || !strings.EqualFold(contactEmail, customer.Email) {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	err = p.client.SetGroupPrimaryContact(ctx, groupID, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to clear group primary contact: %w", err)
 	}
 
 	return nil, nil
 }
 
-func newGroupBuilder(client *galileo.Client) *groupBuilder {
+// primaryContactEmail returns the email of the current primary contact of the group.
+func (g *groupBuilder) primaryContactEmail(ctx context.Context, p *provider, groupID string) (string, error) {
+	groups, _, err := p.client.GetGroupsInfo(ctx, []string{groupID})
+	if err != nil {
+		return "", fmt.Errorf("galileo-ft-connector: failed to get group info: %w", err)
+	}
+
+	if len(groups) == 0 {
+		return "", fmt.Errorf("galileo-ft-connector: group %s not found", groupID)
+	}
+
+	return groups[0].ContactEmail, nil
+}
+
+func newGroupBuilder(
+	providers *providerSet,
+	cache *galileo.ResponseCache,
+	state *syncState,
+	progress *syncProgress,
+	scope *scopeFilter,
+	businesses bool,
+	useExternalID bool,
+) *groupBuilder {
 	return &groupBuilder{
-		client:       client,
-		resourceType: groupResourceType,
+		providers:     providers,
+		resourceType:  groupResourceType,
+		cache:         cache,
+		state:         state,
+		progress:      progress,
+		scope:         scope,
+		businesses:    businesses,
+		useExternalID: useExternalID,
+		descendants:   make(map[string]*rootDescendants),
 	}
 }
@@ -0,0 +1,96 @@
+package connector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPrimaryContactRoundTrip(t *testing.T) {
+	standIn := newGalileoStandIn()
+	standIn.addGroup("10", "", "Acme")
+	standIn.addGroup("20", "", "Globex")
+	standIn.addAccount("1001", "10", "jane@acme.example")
+	standIn.addAccount("2001", "20", "john@globex.example")
+
+	g := newStandInConnector(t, standIn, &Options{CacheTTL: time.Minute, CacheMaxEntries: 100})
+	groups := resourceProvisioner(t, g, groupResourceType)
+	ctx := context.Background()
+
+	// syncGroup lists the groups and returns the group and the members granted its primary contact
+	syncGroup := func() (*v2.Resource, []string) {
+		t.Helper()
+
+		group := findResource(listAll(t, groups, nil), "10")
+		if group == nil {
+			t.Fatal("group 10 not synced")
+		}
+
+		grants, _, _, err := groups.Grants(ctx, group, &pagination.Token{})
+		if err != nil {
+			t.Fatalf("Grants() error = %v", err)
+		}
+
+		return group, grantsOf(grants, GroupPrimaryContact)
+	}
+
+	group, contacts := syncGroup()
+	if len(contacts) != 0 {
+		t.Fatalf("primary contacts before the grant = %v", contacts)
+	}
+
+	entitlement := &v2.Entitlement{Id: "group:10:" + GroupPrimaryContact, Resource: group, Slug: GroupPrimaryContact}
+	member := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "1001"}}
+	other := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "2001"}}
+
+	// an account of another group cannot be the primary contact, it would not be synced as such
+	_, err := groups.Grant(ctx, other, entitlement)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Grant() to an account of another group error = %v, want FailedPrecondition", err)
+	}
+
+	if standIn.callCount(galileo.ModifyGroupEndpoint) != 0 {
+		t.Error("primary contact changed for an account of another group")
+	}
+
+	_, err = groups.Grant(ctx, member, entitlement)
+	if err != nil {
+		t.Fatalf("Grant() error = %v", err)
+	}
+
+	// the granted contact is synced back as granted, and granting it again changes nothing
+	group, contacts = syncGroup()
+	if len(contacts) != 1 || contacts[0] != "1001" {
+		t.Errorf("primary contacts after the grant = %v, want [1001]", contacts)
+	}
+
+	annos, err := groups.Grant(ctx, member, entitlement)
+	if err != nil {
+		t.Fatalf("Grant() of the synced grant error = %v", err)
+	}
+
+	if !annos.Contains(&v2.GrantAlreadyExists{}) {
+		t.Error("Grant() of the synced grant is not reported as already existing")
+	}
+
+	_, err = groups.Revoke(ctx, grant.NewGrant(group, GroupPrimaryContact, member.Id))
+	if err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	_, contacts = syncGroup()
+	if len(contacts) != 0 {
+		t.Errorf("primary contacts after the revoke = %v", contacts)
+	}
+
+	if calls := standIn.callCount(galileo.ModifyGroupEndpoint); calls != 2 {
+		t.Errorf("primary contact changed %d times, want 2", calls)
+	}
+}
@@ -1,67 +1,93 @@
 package connector
 
 import (
-	"fmt"
-	"strconv"
+	"strings"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
-	"github.com/conductorone/baton-sdk/pkg/annotations"
-	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 )
 
 const ResourcesPageSize uint = 50
 
-func annotationsForUserResourceType() annotations.Annotations {
-	annos := annotations.Annotations{}
-	annos.Update(&v2.SkipEntitlementsAndGrants{})
-	return annos
+// entitlementSlug returns the slug of the entitlement,
+// falling back to the last segment of the entitlement ID if the slug is not set.
+func entitlementSlug(entitlement *v2.Entitlement) string {
+	if entitlement.Slug != "" {
+		return entitlement.Slug
+	}
+
+	parts := strings.Split(entitlement.Id, ":")
+	return parts[len(parts)-1]
 }
 
-func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, uint, error) {
-	b := &pagination.Bag{}
-	err := b.Unmarshal(i)
+// accountPRN returns the Galileo account PRN recorded in the profile of a user resource.
+// The resource ID is not necessarily the PRN, as users can be identified by their external ID.
+func accountPRN(resource *v2.Resource) (string, bool) {
+	userTrait, err := rs.GetUserTrait(resource)
 	if err != nil {
-		return nil, 0, err
+		return "", false
 	}
 
-	if b.Current() == nil {
-		b.Push(pagination.PageState{
-			ResourceTypeID: resourceID.ResourceType,
-			ResourceID:     resourceID.Resource,
-		})
+	prn, ok := rs.GetProfileStringValue(userTrait.GetProfile(), "prn")
+	if !ok || prn == "" {
+		return "", false
 	}
 
-	page, err := convertPageToken(b.PageToken())
+	return prn, true
+}
+
+// isSecondaryAccount returns whether the user resource is a secondary account related to a primary account.
+func isSecondaryAccount(resource *v2.Resource) bool {
+	return primaryAccountPRN(resource) != ""
+}
+
+// primaryAccountPRN returns the PRN of the primary account recorded in the profile of a secondary account,
+// or an empty PRN for primary accounts.
+func primaryAccountPRN(resource *v2.Resource) string {
+	userTrait, err := rs.GetUserTrait(resource)
 	if err != nil {
-		return nil, 0, err
+		return ""
 	}
 
-	return b, page, nil
+	primaryAccID, _ := rs.GetProfileStringValue(userTrait.GetProfile(), "primary_account")
+	return primaryAccID
 }
 
-// convertPageToken converts a string token into an int.
-func convertPageToken(token string) (uint, error) {
-	if token == "" {
-		return 1, nil
+// identityVerified returns whether the profile of a user resource records that its customer passed the identity verification.
+func identityVerified(resource *v2.Resource) bool {
+	userTrait, err := rs.GetUserTrait(resource)
+	if err != nil {
+		return false
 	}
 
-	page, err := strconv.ParseUint(token, 10, 32)
+	return userTrait.GetProfile().GetFields()["identity_verified"].GetBoolValue()
+}
+
+// relatedAccountPRNs returns the PRNs of the secondary accounts recorded in the profile of a user resource.
+func relatedAccountPRNs(resource *v2.Resource) []string {
+	userTrait, err := rs.GetUserTrait(resource)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse page token: %w", err)
+		return nil
 	}
 
-	return uint(page), nil
-}
+	values := userTrait.GetProfile().GetFields()["related_accounts"].GetListValue().GetValues()
 
-// prepareNextToken prepares the next page token
-// based on current page and total number of pages there are.
-func prepareNextToken(page uint, totalPages uint) string {
-	var token string
+	rv := make([]string, 0, len(values))
+	for _, v := range values {
+		if prn := v.GetStringValue(); prn != "" {
+			rv = append(rv, prn)
+		}
+	}
+
+	return rv
+}
 
-	next := page + 1
-	if next <= totalPages {
-		token = strconv.FormatUint(uint64(next), 10)
+// relatedAccountsValue returns the PRNs of the secondary accounts of a user as a profile value.
+func relatedAccountsValue(related []string) []interface{} {
+	rv := make([]interface{}, 0, len(related))
+	for _, prn := range related {
+		rv = append(rv, prn)
 	}
 
-	return token
+	return rv
 }
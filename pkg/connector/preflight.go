@@ -0,0 +1,172 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxGroupLevels is the number of levels of a group hierarchy, a root group and five levels below it.
+const maxGroupLevels = 6
+
+// membershipState is what Galileo holds for the account and the group of a grant or revoke.
+type membershipState struct {
+	// currentGroup is the group the account belongs to, empty if none.
+	currentGroup string
+	// depth is the level of the group in its hierarchy, 1 for a root group.
+	depth int
+}
+
+// checkMembership looks up the account and the group of a membership grant or revoke before changing them, and fails if either
+// does not exist or the group is deeper than a Galileo hierarchy can be.
+func (g *groupBuilder) checkMembership(ctx context.Context, p *provider, membership galileo.Membership) (*membershipState, error) {
+	customer, err := p.client.GetCustomer(ctx, membership.AccountID)
+	if err == nil && customer == nil {
+		err = errNoCustomer
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to get account %s: %w", p.resourceID(membership.AccountID), err)
+	}
+
+	currentGroup, err := p.client.GetAccountGroup(ctx, membership.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to get group of account %s: %w", p.resourceID(membership.AccountID), err)
+	}
+
+	depth, err := g.groupDepth(ctx, p, membership.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the checks of a dry run are its outcome, otherwise they are only useful to debug a change
+	l := ctxzap.Extract(ctx)
+	log := l.Debug
+	if p.client.DryRun() {
+		log = l.Info
+	}
+
+	log(
+		"galileo-ft-connector: membership pre-checks passed",
+		zap.String("account", p.resourceID(membership.AccountID)),
+		zap.String("group", p.resourceID(membership.GroupID)),
+		zap.String("entitlement", GroupMembership),
+		zap.String("current_group", currentGroup),
+		zap.Bool("member", currentGroup == membership.GroupID),
+		zap.Int("depth", depth),
+	)
+
+	return &membershipState{
+		currentGroup: currentGroup,
+		depth:        depth,
+	}, nil
+}
+
+// groupDepth returns the level of the group, walking up its parents to the root group.
+func (g *groupBuilder) groupDepth(ctx context.Context, p *provider, groupID string) (int, error) {
+	id := groupID
+	for depth := 1; depth <= maxGroupLevels; depth++ {
+		groups, _, err := p.client.GetGroupsInfo(ctx, []string{id})
+		if err != nil {
+			return 0, fmt.Errorf("galileo-ft-connector: failed to get group %s: %w", p.resourceID(id), err)
+		}
+
+		if len(groups) == 0 {
+			return 0, status.Errorf(codes.NotFound, "galileo-ft-connector: group %s not found", p.resourceID(id))
+		}
+
+		if groups[0].ParentGroupID == "" {
+			return depth, nil
+		}
+
+		id = groups[0].ParentGroupID
+	}
+
+	return 0, status.Errorf(
+		codes.FailedPrecondition,
+		"galileo-ft-connector: group %s is more than %d levels deep",
+		p.resourceID(groupID),
+		maxGroupLevels,
+	)
+}
+
+// grantMembership moves the account to the group, unless it already belongs to it.
+// An account belongs to a single group, so an account of another group leaves it.
+func (g *groupBuilder) grantMembership(ctx context.Context, p *provider, membership galileo.Membership, state *membershipState) (annotations.Annotations, error) {
+	if state.currentGroup == membership.GroupID {
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	if state.currentGroup != "" {
+		ctxzap.Extract(ctx).Info(
+			"galileo-ft-connector: moving account to another group",
+			zap.String("account", p.resourceID(membership.AccountID)),
+			zap.String("from_group", p.resourceID(state.currentGroup)),
+			zap.String("to_group", p.resourceID(membership.GroupID)),
+		)
+	}
+
+	err := p.client.AddAccountToGroup(ctx, membership)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to grant group membership: %w", err)
+	}
+
+	err = verifyMembership(ctx, p, membership, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// revokeMembership removes the account from the group, unless it does not belong to it.
+func (g *groupBuilder) revokeMembership(ctx context.Context, p *provider, membership galileo.Membership, state *membershipState) (annotations.Annotations, error) {
+	// Galileo removes the account from whatever group it belongs to
+	if state.currentGroup != membership.GroupID {
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	err := p.client.RemoveAccountFromGroup(ctx, membership)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to revoke group membership: %w", err)
+	}
+
+	err = verifyMembership(ctx, p, membership, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// verifyMembership reads the group of the account again after a change, and fails if Galileo accepted the change
+// without applying it. Nothing changed in a dry run, so there is nothing to verify.
+func verifyMembership(ctx context.Context, p *provider, membership galileo.Membership, member bool) error {
+	if p.client.DryRun() {
+		return nil
+	}
+
+	currentGroup, err := p.client.GetAccountGroup(ctx, membership.AccountID)
+	if err != nil {
+		return fmt.Errorf("galileo-ft-connector: failed to verify group of account %s: %w", p.resourceID(membership.AccountID), err)
+	}
+
+	if (currentGroup == membership.GroupID) != member {
+		return status.Errorf(
+			codes.Internal,
+			"galileo-ft-connector: Galileo accepted the change of account %s in group %s, but the account is in group %q",
+			p.resourceID(membership.AccountID),
+			p.resourceID(membership.GroupID),
+			currentGroup,
+		)
+	}
+
+	return nil
+}
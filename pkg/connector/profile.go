@@ -0,0 +1,387 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	config "github.com/conductorone/baton-sdk/pb/c1/config/v1"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	actionUpdateProfile = "update_profile"
+	actionOutResource   = "resource"
+	actionOutFields     = "fields"
+	maxAddressChars     = 40
+)
+
+var (
+	phoneChars     = regexp.MustCompile(`[\s().-]`)
+	phonePattern   = regexp.MustCompile(`^\+?[0-9]{7,15}$`)
+	statePattern   = regexp.MustCompile(`^[A-Za-z]{2}$`)
+	postalPattern  = regexp.MustCompile(`^[A-Za-z0-9 -]{3,10}$`)
+	countryPattern = regexp.MustCompile(`^([A-Za-z]{2}|[0-9]{3})$`)
+)
+
+// profileField is a customer profile field the update_profile action can change.
+type profileField struct {
+	name        string
+	displayName string
+	description string
+	// normalize returns the value sent to Galileo, or an error describing the expected format.
+	normalize func(value string) (string, error)
+	set       func(update *galileo.ProfileUpdate, value string)
+}
+
+var profileFields = []profileField{
+	{
+		name:        "email",
+		displayName: "Email",
+		description: "The email address of the cardholder.",
+		normalize: func(value string) (string, error) {
+			addr, err := mail.ParseAddress(value)
+			if err != nil || addr.Address != value {
+				return "", errors.New("must be a plain email address, e.g. jane@example.com")
+			}
+
+			return value, nil
+		},
+		set: func(update *galileo.ProfileUpdate, value string) { update.Email = value },
+	},
+	{
+		name:        "mobile_phone",
+		displayName: "Mobile phone",
+		description: "The mobile phone number of the cardholder, digits only with an optional leading +.",
+		normalize:   normalizePhone,
+		set:         func(update *galileo.ProfileUpdate, value string) { update.MobilePhone = value },
+	},
+	{
+		name:        "home_phone",
+		displayName: "Home phone",
+		description: "The home phone number of the cardholder, digits only with an optional leading +.",
+		normalize:   normalizePhone,
+		set:         func(update *galileo.ProfileUpdate, value string) { update.HomePhone = value },
+	},
+	{
+		name:        "address_1",
+		displayName: "Address line 1",
+		description: "The first line of the cardholder address.",
+		normalize:   normalizeAddress,
+		set:         func(update *galileo.ProfileUpdate, value string) { update.Address1 = value },
+	},
+	{
+		name:        "address_2",
+		displayName: "Address line 2",
+		description: "The second line of the cardholder address.",
+		normalize:   normalizeAddress,
+		set:         func(update *galileo.ProfileUpdate, value string) { update.Address2 = value },
+	},
+	{
+		name:        "city",
+		displayName: "City",
+		description: "The city of the cardholder address.",
+		normalize:   normalizeAddress,
+		set:         func(update *galileo.ProfileUpdate, value string) { update.City = value },
+	},
+	{
+		name:        "state",
+		displayName: "State",
+		description: "The two letter state code of the cardholder address.",
+		normalize: func(value string) (string, error) {
+			if !statePattern.MatchString(value) {
+				return "", errors.New("must be a two letter state code, e.g. CA")
+			}
+
+			return strings.ToUpper(value), nil
+		},
+		set: func(update *galileo.ProfileUpdate, value string) { update.State = value },
+	},
+	{
+		name:        "postal_code",
+		displayName: "Postal code",
+		description: "The postal code of the cardholder address.",
+		normalize: func(value string) (string, error) {
+			if !postalPattern.MatchString(value) {
+				return "", errors.New("must be 3 to 10 letters, digits, spaces or dashes")
+			}
+
+			return value, nil
+		},
+		set: func(update *galileo.ProfileUpdate, value string) { update.PostalCode = value },
+	},
+	{
+		name:        "country_code",
+		displayName: "Country code",
+		description: "The ISO 3166 country code of the cardholder address, alpha-2 or numeric.",
+		normalize: func(value string) (string, error) {
+			if !countryPattern.MatchString(value) {
+				return "", errors.New("must be an ISO 3166 alpha-2 or numeric country code, e.g. US or 840")
+			}
+
+			return strings.ToUpper(value), nil
+		},
+		set: func(update *galileo.ProfileUpdate, value string) { update.CountryCode = value },
+	},
+}
+
+func normalizePhone(value string) (string, error) {
+	phone := phoneChars.ReplaceAllString(value, "")
+	if !phonePattern.MatchString(phone) {
+		return "", errors.New("must have 7 to 15 digits, with an optional leading +")
+	}
+
+	return phone, nil
+}
+
+func normalizeAddress(value string) (string, error) {
+	if len(value) > maxAddressChars {
+		return "", fmt.Errorf("must be at most %d characters", maxAddressChars)
+	}
+
+	return value, nil
+}
+
+// registerUpdateProfile registers the action updating the profile of the customer of an account.
+func (u *userBuilder) registerUpdateProfile(ctx context.Context, registry actions.ActionRegistry) error {
+	arguments := []*config.Field{resourceArgument, userArgument}
+	for _, f := range profileFields {
+		arguments = append(arguments, config.Field_builder{
+			Name:        f.name,
+			DisplayName: f.displayName,
+			Description: f.description,
+			StringField: config.StringField_builder{}.Build(),
+		}.Build())
+	}
+
+	return registry.Register(ctx, v2.BatonActionSchema_builder{
+		Name:        actionUpdateProfile,
+		DisplayName: "Update profile",
+		Description: "Update the email, phones or address of the cardholder, fields left empty are unchanged.",
+		Arguments:   arguments,
+		ReturnTypes: []*config.Field{
+			config.Field_builder{
+				Name:        "success",
+				DisplayName: "Success",
+				BoolField:   config.BoolField_builder{}.Build(),
+			}.Build(),
+			dryRunReturnType,
+			config.Field_builder{
+				Name:             actionOutFields,
+				DisplayName:      "Fields",
+				Description:      "The names of the updated profile fields.",
+				StringSliceField: config.StringSliceField_builder{}.Build(),
+			}.Build(),
+			config.Field_builder{
+				Name:          actionOutResource,
+				DisplayName:   "User",
+				Description:   "The user as synced with its updated profile, not returned in a dry run.",
+				ResourceField: config.ResourceField_builder{}.Build(),
+			}.Build(),
+		},
+	}.Build(), u.updateProfile)
+}
+
+// profileUpdate returns the validated update of the action arguments, and the names of the updated fields.
+func profileUpdate(args *structpb.Struct) (*galileo.ProfileUpdate, []string, error) {
+	update := &galileo.ProfileUpdate{}
+
+	var updated, invalid []string
+	for _, f := range profileFields {
+		value, ok := actions.GetStringArg(args, f.name)
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			continue
+		}
+
+		normalized, err := f.normalize(value)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s %s", f.name, err))
+			continue
+		}
+
+		f.set(update, normalized)
+		updated = append(updated, f.name)
+	}
+
+	if len(invalid) > 0 {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "galileo-ft-connector: invalid profile: %s", strings.Join(invalid, "; "))
+	}
+
+	if len(updated) == 0 {
+		return nil, nil, status.Error(codes.InvalidArgument, "galileo-ft-connector: no profile field to update")
+	}
+
+	return update, updated, nil
+}
+
+// userPlacement is where a user is synced: under its group, along with its primary account or its related accounts.
+type userPlacement struct {
+	parent       *v2.ResourceId
+	primaryAccID string
+	related      []string
+}
+
+// actionUserPlacement returns the placement of the synced user argument if given, otherwise the one of the account as listed:
+// group members are synced under their group, or their business for members of root groups when businesses are synced,
+// with their related accounts.
+// Accounts of no group are secondary accounts synced under the group of their primary account,
+// which Galileo does not return for a secondary account, so their synced user is required.
+func (u *userBuilder) actionUserPlacement(ctx context.Context, p *provider, resourceID *v2.ResourceId, accID string, args *structpb.Struct) (*userPlacement, error) {
+	if user := syncedUser(args, resourceID); user != nil {
+		return &userPlacement{
+			parent:       user.GetParentResourceId(),
+			primaryAccID: primaryAccountPRN(user),
+			related:      relatedAccountPRNs(user),
+		}, nil
+	}
+
+	groupID, err := p.client.GetAccountGroup(ctx, accID)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to get group of account %s: %w", p.resourceID(accID), err)
+	}
+
+	if groupID == "" {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"galileo-ft-connector: account %s is a secondary account of no group, pass the synced user in the %s argument",
+			p.resourceID(accID),
+			actionArgUser,
+		)
+	}
+
+	// members of root groups are synced under their business when business resources are synced
+	groupType, err := syncedGroupType(ctx, p, groupID, u.businesses)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := rs.NewResourceID(groupType, p.resourceID(groupID))
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to create group resource ID: %w", err)
+	}
+
+	accounts, err := p.client.ListRelatedAccounts(ctx, accID)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to list related accounts of account %s: %w", p.resourceID(accID), err)
+	}
+
+	related := make([]string, 0, len(accounts))
+	for _, acc := range accounts {
+		if u.scope.account(p, acc.ProdID) {
+			related = append(related, acc.ID)
+		}
+	}
+
+	return &userPlacement{parent: parent, related: related}, nil
+}
+
+// updateProfile updates the profile of the customer of an account, and returns its user as synced with the new profile.
+// A dry run only validates and logs the update, it returns no user as the profile is unchanged.
+func (u *userBuilder) updateProfile(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	// the returned user is read from Galileo, not from responses cached during the sync
+	ctx = galileo.WithNoCache(ctx)
+
+	p, accID, err := u.actionAccount(ctx, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	update, updated, err := profileUpdate(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dryRun := p.client.DryRun()
+
+	// the placement is resolved before the update, so that a user that cannot be returned is not updated
+	var placement *userPlacement
+	if !dryRun {
+		resourceID, err := actions.RequireResourceIDArg(args, actionArgResource)
+		if err != nil {
+			return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		placement, err = u.actionUserPlacement(ctx, p, resourceID, accID, args)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	err = p.client.UpdateAccount(ctx, accID, update)
+	if err != nil {
+		// Galileo validates the format of the fields again, e.g. against the address rules of the program
+		var galileoErr *galileo.ErrorResponse
+		if errors.As(err, &galileoErr) && status.Code(err) == codes.InvalidArgument {
+			return nil, nil, status.Errorf(
+				codes.InvalidArgument,
+				"galileo-ft-connector: Galileo rejected the profile of account %s: %s",
+				p.resourceID(accID),
+				galileoErr.Status,
+			)
+		}
+
+		return nil, nil, fmt.Errorf("galileo-ft-connector: failed to update account %s: %w", p.resourceID(accID), err)
+	}
+
+	fieldsField := actions.NewStringListReturnField(actionOutFields, updated)
+	if dryRun {
+		return actions.NewReturnValues(true, actions.NewBoolReturnField(actionOutDryRun, true), fieldsField), nil, nil
+	}
+
+	ctxzap.Extract(ctx).Info(
+		"galileo-ft-connector: cardholder profile updated",
+		zap.String("account", p.resourceID(accID)),
+		zap.Strings("fields", updated),
+	)
+
+	customer, err := p.client.GetCustomer(ctx, accID)
+	if err == nil && customer == nil {
+		err = errNoCustomer
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("galileo-ft-connector: profile updated but failed to get customer of account %s: %w", p.resourceID(accID), err)
+	}
+
+	// the user is identified as when it is listed
+	resourceID := p.resourceID(accID)
+	if u.useExternalID && customer.ExternalID != "" {
+		resourceID = p.resourceID(customer.ExternalID)
+	}
+
+	identity := u.identityProfile || u.kycVerified
+	resource, err := userResource(
+		p,
+		resourceID,
+		accID,
+		placement.primaryAccID,
+		placement.related,
+		customer,
+		identity,
+		u.accountUsage(ctx, p, accID),
+		placement.parent,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("galileo-ft-connector: failed to create user resource: %w", err)
+	}
+
+	resourceField, err := actions.NewResourceReturnField(actionOutResource, resource)
+	if err != nil {
+		return nil, nil, fmt.Errorf("galileo-ft-connector: failed to return user resource: %w", err)
+	}
+
+	return actions.NewReturnValues(true, actions.NewBoolReturnField(actionOutDryRun, false), fieldsField, resourceField), nil, nil
+}
@@ -0,0 +1,55 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+func TestActionUserPlacement(t *testing.T) {
+	standIn := newGalileoStandIn()
+	standIn.addGroup("10", "", "Acme")
+	standIn.addGroup("11", "10", "Sales")
+	standIn.addAccount("1001", "10", "jane@acme.example").related = []string{"1002"}
+	standIn.addAccount("1002", "", "jim@acme.example")
+	standIn.addAccount("1101", "11", "john@acme.example")
+
+	tests := []struct {
+		name       string
+		businesses bool
+		accID      string
+		parent     *v2.ResourceType
+	}{
+		{name: "member of a root group", accID: "1001", parent: groupResourceType},
+		{name: "member of a department", accID: "1101", parent: groupResourceType},
+		{name: "member of a business", businesses: true, accID: "1001", parent: businessResourceType},
+		{name: "member of a department of a business", businesses: true, accID: "1101", parent: groupResourceType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newStandInConnector(t, standIn, &Options{BusinessResources: tt.businesses})
+			u, ok := resourceSyncer(t, g, userResourceType).(*userBuilder)
+			if !ok {
+				t.Fatal("user syncer is not a user builder")
+			}
+
+			p := g.providers.list[0]
+			resourceID := &v2.ResourceId{ResourceType: userResourceType.Id, Resource: tt.accID}
+
+			placement, err := u.actionUserPlacement(context.Background(), p, resourceID, tt.accID, nil)
+			if err != nil {
+				t.Fatalf("actionUserPlacement() error = %v", err)
+			}
+
+			if placement.parent.ResourceType != tt.parent.Id || placement.parent.Resource != standIn.accounts[tt.accID].group {
+				t.Errorf("parent = %s:%s, want %s:%s", placement.parent.ResourceType, placement.parent.Resource, tt.parent.Id, standIn.accounts[tt.accID].group)
+			}
+
+			if len(placement.related) != len(standIn.accounts[tt.accID].related) {
+				t.Errorf("related accounts = %v, want %v", placement.related, standIn.accounts[tt.accID].related)
+			}
+		})
+	}
+}
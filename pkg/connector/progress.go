@@ -0,0 +1,412 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/types"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// Sync phases, named after the resource type and the builder method.
+const (
+	phaseGroupList    = "group.list"
+	phaseGroupGrants  = "group.grants"
+	phaseBusinessList = "business.list"
+	phaseUserList     = "user.list"
+	phaseUserGrants   = "user.grants"
+	phaseUserUsage    = "user.usage"
+)
+
+type phaseKey struct{}
+
+// phaseStats holds the time spent and the API calls made in a sync phase.
+type phaseStats struct {
+	Pages    int64         `json:"pages"`
+	APICalls int64         `json:"api_calls"`
+	Cached   int64         `json:"cached_calls"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// accountIssue is an account that was skipped or failed during the sync.
+type accountIssue struct {
+	Account string `json:"account"`
+	Group   string `json:"group,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// syncReport is the summary of a sync, written to the logs and to the report file.
+type syncReport struct {
+	StartedAt       time.Time              `json:"started_at"`
+	FinishedAt      time.Time              `json:"finished_at"`
+	Duration        time.Duration          `json:"duration_ns"`
+	Resumed         bool                   `json:"resumed"`
+	Scope           map[string]interface{} `json:"scope"`
+	RootGroups      int64                  `json:"root_groups"`
+	ChildGroups     int64                  `json:"child_groups"`
+	Accounts        int64                  `json:"accounts"`
+	RelatedAccounts int64                  `json:"related_accounts"`
+	APICalls        int64                  `json:"api_calls"`
+	CachedCalls     int64                  `json:"cached_calls"`
+	UsageCalls      int64                  `json:"usage_calls"`
+	UsageSummaries  int64                  `json:"usage_summaries"`
+	UsageSkipped    int64                  `json:"usage_skipped"`
+	Phases          map[string]*phaseStats `json:"phases"`
+	Skipped         int64                  `json:"skipped"`
+	Failed          int64                  `json:"failed"`
+	// SkippedAccounts and FailedAccounts detail the accounts counted by Skipped and Failed.
+	// They are not kept in the page tokens, so a resumed sync only details the accounts since its resumption.
+	SkippedAccounts []accountIssue `json:"skipped_accounts"`
+	FailedAccounts  []accountIssue `json:"failed_accounts"`
+}
+
+// syncProgress tracks what has been synced so far, logs the progress periodically
+// and reports a summary at the end of the sync.
+type syncProgress struct {
+	mu         sync.Mutex
+	interval   time.Duration
+	reportPath string
+	scope      map[string]interface{}
+	lastLog    time.Time
+	report     *syncReport
+}
+
+func newSyncProgress(interval time.Duration, reportPath string, scope map[string]interface{}) *syncProgress {
+	return &syncProgress{
+		interval:   interval,
+		reportPath: reportPath,
+		scope:      scope,
+	}
+}
+
+// Reset starts tracking a new sync. It is called at the start of every sync.
+func (s *syncProgress) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.start()
+}
+
+// start starts a new report, s.mu must be held.
+func (s *syncProgress) start() {
+	now := time.Now()
+	s.lastLog = now
+	s.report = &syncReport{
+		StartedAt:       now,
+		Scope:           s.scope,
+		Phases:          make(map[string]*phaseStats),
+		SkippedAccounts: []accountIssue{},
+		FailedAccounts:  []accountIssue{},
+	}
+}
+
+// snapshot returns a copy of the report of the current sync, kept in the page tokens so a resumed sync restores it.
+// Only the counts are kept, so the size of the tokens does not grow with the number of skipped or failed accounts.
+func (s *syncProgress) snapshot() (*syncReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(s.report)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &syncReport{}
+	err = json.Unmarshal(data, report)
+	if err != nil {
+		return nil, err
+	}
+
+	report.SkippedAccounts = nil
+	report.FailedAccounts = nil
+
+	return report, nil
+}
+
+// resume restores the report of a sync resumed from a page token, unless the sync is already tracked by this process.
+func (s *syncProgress) resume(report *syncReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report != nil || report == nil {
+		return
+	}
+
+	if report.Phases == nil {
+		report.Phases = make(map[string]*phaseStats)
+	}
+
+	report.SkippedAccounts = []accountIssue{}
+	report.FailedAccounts = []accountIssue{}
+
+	report.Resumed = true
+	s.lastLog = time.Now()
+	s.report = report
+}
+
+// resumeLater starts a report for a sync resumed after its groups were listed, whose page tokens
+// carried no report. The counts of the report then only start from the resumption.
+func (s *syncProgress) resumeLater() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report != nil {
+		return
+	}
+
+	s.start()
+	s.report.Resumed = true
+}
+
+// update applies f to the report of the current sync, if any.
+func (s *syncProgress) update(f func(r *syncReport)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report != nil {
+		f(s.report)
+	}
+}
+
+// startPhase tracks the time spent and API calls made until the returned function is called.
+// A phase started without a report is part of a sync resumed by another process after its groups were listed.
+func (s *syncProgress) startPhase(ctx context.Context, name string) (context.Context, func()) {
+	start := time.Now()
+
+	if name != phaseGroupList {
+		s.resumeLater()
+	}
+
+	return context.WithValue(ctx, phaseKey{}, name), func() {
+		s.update(func(r *syncReport) {
+			phase := r.phase(name)
+			phase.Pages++
+			phase.Duration += time.Since(start)
+		})
+
+		s.logProgress(ctx)
+	}
+}
+
+// observeCall counts a Galileo API call in the phase of the context.
+func (s *syncProgress) observeCall(ctx context.Context, _ string, cached bool) {
+	name, _ := ctx.Value(phaseKey{}).(string)
+
+	s.update(func(r *syncReport) {
+		var phase *phaseStats
+		if name != "" {
+			phase = r.phase(name)
+		}
+
+		if cached {
+			r.CachedCalls++
+			if phase != nil {
+				phase.Cached++
+			}
+
+			return
+		}
+
+		r.APICalls++
+		if phase != nil {
+			phase.APICalls++
+		}
+	})
+}
+
+func (s *syncProgress) addGroups(roots, children int) {
+	s.update(func(r *syncReport) {
+		r.RootGroups += int64(roots)
+		r.ChildGroups += int64(children)
+	})
+}
+
+func (s *syncProgress) addAccounts(accounts, related int) {
+	s.update(func(r *syncReport) {
+		r.Accounts += int64(accounts)
+		r.RelatedAccounts += int64(related)
+	})
+}
+
+// takeUsageCall reserves a usage history call, it returns false once the sync made the given maximum of calls.
+// Zero allows any number of calls.
+func (s *syncProgress) takeUsageCall(limit int) bool {
+	ok := true
+	s.update(func(r *syncReport) {
+		if limit > 0 && r.UsageCalls >= int64(limit) {
+			ok = false
+			return
+		}
+
+		r.UsageCalls++
+	})
+
+	return ok
+}
+
+// usageSummarized counts an account with a usage summary, or without one if it was skipped.
+func (s *syncProgress) usageSummarized(skipped bool) {
+	s.update(func(r *syncReport) {
+		if skipped {
+			r.UsageSkipped++
+		} else {
+			r.UsageSummaries++
+		}
+	})
+}
+
+func (s *syncProgress) accountSkipped(accID, groupID, reason string) {
+	s.update(func(r *syncReport) {
+		r.Skipped++
+		r.SkippedAccounts = append(r.SkippedAccounts, accountIssue{Account: accID, Group: groupID, Reason: reason})
+	})
+}
+
+// accountFailed records an account that failed to sync and returns the number of failed accounts in the sync.
+func (s *syncProgress) accountFailed(accID, groupID string, err error) int {
+	failed := 1
+	s.update(func(r *syncReport) {
+		r.Failed++
+		r.FailedAccounts = append(r.FailedAccounts, accountIssue{Account: accID, Group: groupID, Reason: err.Error()})
+		failed = int(r.Failed)
+	})
+
+	return failed
+}
+
+// logProgress logs the counts of the sync if the progress interval elapsed since the last log.
+func (s *syncProgress) logProgress(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report == nil || s.interval <= 0 || time.Since(s.lastLog) < s.interval {
+		return
+	}
+
+	s.lastLog = time.Now()
+	ctxzap.Extract(ctx).Info("galileo-ft-connector: sync progress", s.report.fields()...)
+}
+
+// Finish logs the summary of the sync and writes it to the report file, if configured.
+func (s *syncProgress) Finish(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.report == nil {
+		return nil
+	}
+
+	report := s.report
+	s.report = nil
+
+	report.FinishedAt = time.Now()
+	report.Duration = report.FinishedAt.Sub(report.StartedAt)
+
+	fields := append(report.fields(),
+		zap.Duration("duration", report.Duration),
+		zap.Any("phases", report.Phases),
+	)
+	ctxzap.Extract(ctx).Info("galileo-ft-connector: sync summary", fields...)
+
+	if s.reportPath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("galileo-ft-connector: failed to marshal sync report: %w", err)
+	}
+
+	err = os.WriteFile(s.reportPath, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("galileo-ft-connector: failed to write sync report: %w", err)
+	}
+
+	return nil
+}
+
+func (r *syncReport) phase(name string) *phaseStats {
+	phase, ok := r.Phases[name]
+	if !ok {
+		phase = &phaseStats{}
+		r.Phases[name] = phase
+	}
+
+	return phase
+}
+
+func (r *syncReport) fields() []zap.Field {
+	return []zap.Field{
+		zap.Duration("elapsed", time.Since(r.StartedAt)),
+		zap.Bool("resumed", r.Resumed),
+		zap.Int64("root_groups", r.RootGroups),
+		zap.Int64("child_groups", r.ChildGroups),
+		zap.Int64("accounts", r.Accounts),
+		zap.Int64("related_accounts", r.RelatedAccounts),
+		zap.Int64("api_calls", r.APICalls),
+		zap.Int64("cached_calls", r.CachedCalls),
+		zap.Int64("usage_summaries", r.UsageSummaries),
+		zap.Int64("usage_skipped", r.UsageSkipped),
+		zap.Int64("skipped_accounts", r.Skipped),
+		zap.Int64("failed_accounts", r.Failed),
+	}
+}
+
+// reportingServer reports the sync summary when the SDK cleans up the connector, at the end of every sync.
+// It also passes the sync of the listing requests to the builders in their context, which the SDK only passes
+// to the session store of its own resource syncers.
+type reportingServer struct {
+	types.ConnectorServer
+	progress *syncProgress
+}
+
+func (s *reportingServer) Cleanup(ctx context.Context, request *v2.ConnectorServiceCleanupRequest) (*v2.ConnectorServiceCleanupResponse, error) {
+	err := s.progress.Finish(ctx)
+	if err != nil {
+		ctxzap.Extract(ctx).Error("galileo-ft-connector: failed to report sync", zap.Error(err))
+	}
+
+	return s.ConnectorServer.Cleanup(ctx, request)
+}
+
+func (s *reportingServer) ListResources(ctx context.Context, request *v2.ResourcesServiceListResourcesRequest) (*v2.ResourcesServiceListResourcesResponse, error) {
+	return s.ConnectorServer.ListResources(sessions.SetSyncIDInContext(ctx, request.GetActiveSyncId()), request)
+}
+
+func (s *reportingServer) ListEntitlements(ctx context.Context, request *v2.EntitlementsServiceListEntitlementsRequest) (*v2.EntitlementsServiceListEntitlementsResponse, error) {
+	return s.ConnectorServer.ListEntitlements(sessions.SetSyncIDInContext(ctx, request.GetActiveSyncId()), request)
+}
+
+func (s *reportingServer) ListGrants(ctx context.Context, request *v2.GrantsServiceListGrantsRequest) (*v2.GrantsServiceListGrantsResponse, error) {
+	return s.ConnectorServer.ListGrants(sessions.SetSyncIDInContext(ctx, request.GetActiveSyncId()), request)
+}
+
+// NewConnectorServer returns the connector server of the connector, reporting a summary at the end of every sync.
+// The session store of the connector, if any, is cleared by the SDK at the end of every sync.
+func NewConnectorServer(ctx context.Context, g *Galileo, opts ...connectorbuilder.Opt) (types.ConnectorServer, error) {
+	if g.opts.SessionStore != nil {
+		opts = append(opts, connectorbuilder.WithSessionStore(g.opts.SessionStore))
+	}
+
+	c, err := connectorbuilder.NewConnector(ctx, g, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reportingServer{
+		ConnectorServer: c,
+		progress:        g.progress,
+	}, nil
+}
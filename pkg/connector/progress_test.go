@@ -0,0 +1,134 @@
+package connector
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGroupsCursor(t *testing.T) {
+	cursor, err := parseGroupsCursor("")
+	if err != nil {
+		t.Fatalf("parseGroupsCursor() error = %v", err)
+	}
+
+	cursor.Pages = 2
+	cursor.Roots = []string{"1", "2"}
+	cursor.Names = []string{"Acme", "Globex"}
+	cursor.Included = []string{"2"}
+	cursor.Chunk = 3
+
+	token, err := cursor.token()
+	if err != nil {
+		t.Fatalf("token() error = %v", err)
+	}
+
+	resumed, err := parseGroupsCursor(token)
+	if err != nil {
+		t.Fatalf("parseGroupsCursor() error = %v", err)
+	}
+
+	if resumed.Page != 1 || resumed.Chunk != 3 || resumed.rootName() != "Acme" || !resumed.included("2") || resumed.included("1") {
+		t.Errorf("resumed cursor = %+v", resumed)
+	}
+
+	// the descendants of the second root group follow, then the next page
+	resumed.nextRoot()
+	if resumed.Root != 1 || resumed.Chunk != 0 || resumed.rootName() != "Globex" {
+		t.Errorf("cursor after the first root group = %+v", resumed)
+	}
+
+	resumed.nextRoot()
+	if resumed.Page != 2 || resumed.Roots != nil || resumed.Names != nil || resumed.Included != nil {
+		t.Errorf("cursor after the last root group = %+v", resumed)
+	}
+
+	// the last page is followed by no token
+	resumed.nextPage()
+	token, err = resumed.token()
+	if err != nil || token != "" {
+		t.Errorf("token() after the last page = %q, %v", token, err)
+	}
+}
+
+func TestSyncProgressResume(t *testing.T) {
+	progress := newSyncProgress(0, "", map[string]interface{}{"include_groups": []string{"42"}})
+	progress.Reset()
+	progress.addGroups(2, 5)
+	progress.addAccounts(10, 3)
+
+	pageToken := func() string {
+		t.Helper()
+
+		snapshot, err := progress.snapshot()
+		if err != nil {
+			t.Fatalf("snapshot() error = %v", err)
+		}
+
+		token, err := (&groupsPageToken{Providers: "providers", Progress: snapshot}).marshal()
+		if err != nil {
+			t.Fatalf("marshal() error = %v", err)
+		}
+
+		return token
+	}
+
+	before := len(pageToken())
+
+	for i := 0; i < 500; i++ {
+		progress.accountSkipped(fmt.Sprintf("acc-%d", i), "42", "product out of scope")
+	}
+
+	failed := progress.accountFailed("acc-failed", "42", errors.New("account not found"))
+	if failed != 1 {
+		t.Errorf("accountFailed() = %d, want 1", failed)
+	}
+
+	// the token keeps the counts, not the skipped and failed accounts
+	token := pageToken()
+	if len(token) > before+100 {
+		t.Errorf("page token grew from %d to %d bytes with the skipped accounts", before, len(token))
+	}
+
+	parsed, err := parseGroupsPageToken(token)
+	if err != nil {
+		t.Fatalf("parseGroupsPageToken() error = %v", err)
+	}
+
+	if parsed.Providers != "providers" {
+		t.Errorf("providers page state = %q", parsed.Providers)
+	}
+
+	// another process resumes the sync from the token
+	resumed := newSyncProgress(0, "", nil)
+	resumed.resume(parsed.Progress)
+
+	if got := resumed.accountFailed("acc-other", "42", errors.New("account not found")); got != 2 {
+		t.Errorf("accountFailed() after resumption = %d, want 2", got)
+	}
+
+	report := resumed.report
+	if !report.Resumed {
+		t.Error("resumed report is not marked as resumed")
+	}
+
+	if report.RootGroups != 2 || report.ChildGroups != 5 || report.Accounts != 10 || report.RelatedAccounts != 3 {
+		t.Errorf("resumed counts = %d root groups, %d child groups, %d accounts, %d related accounts",
+			report.RootGroups, report.ChildGroups, report.Accounts, report.RelatedAccounts)
+	}
+
+	if report.Skipped != 500 || report.Failed != 2 {
+		t.Errorf("resumed issues = %d skipped, %d failed, want 500 and 2", report.Skipped, report.Failed)
+	}
+
+	if len(report.SkippedAccounts) != 0 || len(report.FailedAccounts) != 1 {
+		t.Errorf("resumed report details %d skipped and %d failed accounts, want only the failure since the resumption",
+			len(report.SkippedAccounts), len(report.FailedAccounts))
+	}
+
+	// a sync tracked by the process is not replaced by the token of a page
+	resumed.resume(&syncReport{})
+	if resumed.report != report {
+		t.Error("resume() replaced the report of the sync tracked by the process")
+	}
+}
@@ -0,0 +1,95 @@
+package connector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+const providerSeparator = "/"
+
+// provider is a single Galileo program synced by the connector.
+type provider struct {
+	// label namespaces the resources of the provider, it is empty for a single unlabeled provider.
+	label  string
+	client *galileo.Client
+}
+
+// resourceID returns the resource ID of a Galileo object of this provider.
+// Resources of labeled providers are prefixed with the label so IDs do not collide across programs,
+// resources of an unlabeled provider keep the plain Galileo ID.
+func (p *provider) resourceID(id string) string {
+	if p.label == "" {
+		return id
+	}
+
+	return p.label + providerSeparator + id
+}
+
+// providerSet holds all the Galileo programs synced by the connector, in the configured order.
+type providerSet struct {
+	list    []*provider
+	byLabel map[string]*provider
+}
+
+func newProviderSet(list []*provider) (*providerSet, error) {
+	byLabel := make(map[string]*provider, len(list))
+	for _, p := range list {
+		if strings.Contains(p.label, providerSeparator) {
+			return nil, fmt.Errorf("provider label %q must not contain %q", p.label, providerSeparator)
+		}
+
+		if _, ok := byLabel[p.label]; ok {
+			return nil, fmt.Errorf("duplicate provider label %q", p.label)
+		}
+
+		byLabel[p.label] = p
+	}
+
+	return &providerSet{
+		list:    list,
+		byLabel: byLabel,
+	}, nil
+}
+
+// get returns the provider with the given label.
+func (s *providerSet) get(label string) (*provider, error) {
+	p, ok := s.byLabel[label]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", label)
+	}
+
+	return p, nil
+}
+
+// parseResourceID returns the provider a resource belongs to and the Galileo ID of the resource.
+func (s *providerSet) parseResourceID(resourceID string) (*provider, string, error) {
+	if label, id, ok := strings.Cut(resourceID, providerSeparator); ok {
+		if p, ok := s.byLabel[label]; ok && label != "" {
+			return p, id, nil
+		}
+	}
+
+	p, err := s.get("")
+	if err != nil {
+		return nil, "", fmt.Errorf("no provider found for resource %s", resourceID)
+	}
+
+	return p, resourceID, nil
+}
+
+// parseAccount returns the provider and the account PRN of a user resource.
+func (s *providerSet) parseAccount(resource *v2.Resource) (*provider, string, error) {
+	p, id, err := s.parseResourceID(resource.Id.Resource)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if prn, ok := accountPRN(resource); ok {
+		return p, prn, nil
+	}
+
+	return p, id, nil
+}
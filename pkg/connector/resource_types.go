@@ -10,7 +10,6 @@ var (
 		Id:          "user",
 		DisplayName: "User",
 		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
-		Annotations: annotationsForUserResourceType(),
 	}
 
 	// The group resource type is for all group objects from the database.
@@ -19,4 +18,17 @@ var (
 		DisplayName: "Group",
 		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
 	}
+
+	// The business resource type is for the root groups, the employers or businesses the department groups belong to.
+	businessResourceType = &v2.ResourceType{
+		Id:          "business",
+		DisplayName: "Business",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+	}
+
+	// The identity verification resource type holds the KYC verification state of the customers of a provider.
+	verificationResourceType = &v2.ResourceType{
+		Id:          "identity_verification",
+		DisplayName: "Identity verification",
+	}
 )
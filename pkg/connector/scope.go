@@ -0,0 +1,177 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+)
+
+// Scope selects the part of the Galileo programs that is synced.
+// Group and product IDs and group paths of labeled providers are prefixed with the label, like resource IDs.
+type Scope struct {
+	// IncludeGroups restricts the sync to the subtrees of these groups, selected by ID or external ID.
+	IncludeGroups []string
+	// ExcludeGroups skips the subtrees of these groups, selected by ID or external ID.
+	ExcludeGroups []string
+	// IncludePaths restricts the sync to the subtrees of these groups, selected by the names of the groups
+	// from their root group, separated by "/".
+	IncludePaths []string
+	// ExcludePaths skips the subtrees of these groups, selected by path as IncludePaths.
+	ExcludePaths []string
+	// IncludeProducts restricts the accounts synced to these products.
+	IncludeProducts []string
+	// ExcludeProducts skips the accounts of these products.
+	ExcludeProducts []string
+}
+
+// scopeFilter applies a Scope to the groups and accounts listed.
+// Filters are applied on the data returned by list calls, before any per-group or per-account call is made,
+// except for the external IDs of descendant groups and the products of group members,
+// which are only returned by getGroupsInfo and getAccountOverview.
+type scopeFilter struct {
+	scope           Scope
+	includeGroups   map[string]bool
+	excludeGroups   map[string]bool
+	includePaths    map[string]bool
+	excludePaths    map[string]bool
+	includeProducts map[string]bool
+	excludeProducts map[string]bool
+}
+
+func newScopeFilter(scope Scope) *scopeFilter {
+	return &scopeFilter{
+		scope:           scope,
+		includeGroups:   toSet(scope.IncludeGroups),
+		excludeGroups:   toSet(scope.ExcludeGroups),
+		includePaths:    toSet(scope.IncludePaths),
+		excludePaths:    toSet(scope.ExcludePaths),
+		includeProducts: toSet(scope.IncludeProducts),
+		excludeProducts: toSet(scope.ExcludeProducts),
+	}
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		if v != "" {
+			set[v] = true
+		}
+	}
+
+	return set
+}
+
+// rootGroup returns whether the subtree of the root group must be listed at all,
+// and whether the root group itself, with all its descendants, is in scope.
+func (f *scopeFilter) rootGroup(p *provider, group *galileo.Group) (bool, bool) {
+	if f.excluded(p, group.ID, group.ExternalID, group.Name) {
+		return false, false
+	}
+
+	if len(f.includeGroups) == 0 && len(f.includePaths) == 0 {
+		return true, true
+	}
+
+	if f.included(p, group.ID, group.ExternalID, group.Name) {
+		return true, true
+	}
+
+	// descendants of the root group may still be included
+	return true, false
+}
+
+// selectsGroups returns whether groups are selected by ID, in which case the external IDs of the descendants
+// of root groups are needed to apply the scope.
+func (f *scopeFilter) selectsGroups() bool {
+	return len(f.includeGroups) > 0 || len(f.excludeGroups) > 0
+}
+
+// descendants returns the IDs of the groups of the hierarchy that are in scope,
+// given the path of their parent and whether it is in scope.
+// The external IDs of the groups are looked up in externalIDs, which is nil unless groups are selected by ID.
+func (f *scopeFilter) descendants(
+	p *provider,
+	hierarchy []galileo.GroupHierarchy,
+	parentPath string,
+	included bool,
+	externalIDs map[string]string,
+) []string {
+	var ids []string
+	for _, g := range hierarchy {
+		path := parentPath + "/" + g.Name
+		if f.excluded(p, g.ID, externalIDs[g.ID], path) {
+			continue
+		}
+
+		in := included || f.included(p, g.ID, externalIDs[g.ID], path)
+		if in {
+			ids = append(ids, g.ID)
+		}
+
+		ids = append(ids, f.descendants(p, g.Children, path, in, externalIDs)...)
+	}
+
+	return ids
+}
+
+func (f *scopeFilter) excluded(p *provider, id, externalID, path string) bool {
+	return f.excludeGroups[p.resourceID(id)] ||
+		(externalID != "" && f.excludeGroups[p.resourceID(externalID)]) ||
+		f.excludePaths[p.resourceID(path)]
+}
+
+func (f *scopeFilter) included(p *provider, id, externalID, path string) bool {
+	return f.includeGroups[p.resourceID(id)] ||
+		(externalID != "" && f.includeGroups[p.resourceID(externalID)]) ||
+		f.includePaths[p.resourceID(path)]
+}
+
+// member returns whether the group member is in scope given the product of its account.
+// The product is read from the account overview, which is cached for the customer of the member fetched afterwards.
+// Members whose overview cannot be fetched are kept, the failure is handled when their customer is fetched.
+func (f *scopeFilter) member(ctx context.Context, p *provider, accID string) bool {
+	if len(f.includeProducts) == 0 && len(f.excludeProducts) == 0 {
+		return true
+	}
+
+	overview, err := p.client.GetAccountOverview(ctx, accID)
+	if err != nil || overview.ProductID == "" {
+		return true
+	}
+
+	return f.account(p, overview.ProductID)
+}
+
+// account returns whether an account of the given product is in scope.
+func (f *scopeFilter) account(p *provider, productID string) bool {
+	if productID == "" {
+		return len(f.includeProducts) == 0
+	}
+
+	if f.excludeProducts[p.resourceID(productID)] {
+		return false
+	}
+
+	return len(f.includeProducts) == 0 || f.includeProducts[p.resourceID(productID)]
+}
+
+// describe returns the applied scope, recorded in the connector metadata and the sync report.
+func (f *scopeFilter) describe() map[string]interface{} {
+	return map[string]interface{}{
+		"include_groups":   toList(f.scope.IncludeGroups),
+		"exclude_groups":   toList(f.scope.ExcludeGroups),
+		"include_paths":    toList(f.scope.IncludePaths),
+		"exclude_paths":    toList(f.scope.ExcludePaths),
+		"include_products": toList(f.scope.IncludeProducts),
+		"exclude_products": toList(f.scope.ExcludeProducts),
+	}
+}
+
+func toList(values []string) []interface{} {
+	rv := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		rv = append(rv, v)
+	}
+
+	return rv
+}
@@ -0,0 +1,198 @@
+package connector
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+)
+
+// scopeHierarchy is the hierarchy of the Acme root group used by the scope tests:
+// Acme (10) > Sales (11, sales-ext) > East (12, east-ext) > North (14), and Acme (10) > Marketing (13).
+var scopeHierarchy = []galileo.GroupHierarchy{
+	{ID: "11", Name: "Sales", Children: []galileo.GroupHierarchy{
+		{ID: "12", Name: "East", Children: []galileo.GroupHierarchy{
+			{ID: "14", Name: "North"},
+		}},
+	}},
+	{ID: "13", Name: "Marketing"},
+}
+
+var scopeExternalIDs = map[string]string{"11": "sales-ext", "12": "east-ext"}
+
+func TestScopeRootGroup(t *testing.T) {
+	root := &galileo.Group{ID: "10", ExternalID: "acme-ext", Name: "Acme"}
+
+	tests := []struct {
+		name       string
+		label      string
+		scope      Scope
+		wantListed bool
+		wantWhole  bool
+	}{
+		{name: "no scope", wantListed: true, wantWhole: true},
+		{name: "included by ID", scope: Scope{IncludeGroups: []string{"10"}}, wantListed: true, wantWhole: true},
+		{name: "included by external ID", scope: Scope{IncludeGroups: []string{"acme-ext"}}, wantListed: true, wantWhole: true},
+		{name: "included by path", scope: Scope{IncludePaths: []string{"Acme"}}, wantListed: true, wantWhole: true},
+		{name: "descendant included", scope: Scope{IncludePaths: []string{"Acme/Sales"}}, wantListed: true},
+		{name: "other group included", scope: Scope{IncludeGroups: []string{"20"}}, wantListed: true},
+		{name: "excluded by ID", scope: Scope{ExcludeGroups: []string{"10"}}},
+		{name: "excluded by external ID", scope: Scope{ExcludeGroups: []string{"acme-ext"}}},
+		{name: "excluded by path", scope: Scope{ExcludePaths: []string{"Acme"}}},
+		{name: "exclusion wins", scope: Scope{IncludeGroups: []string{"10"}, ExcludePaths: []string{"Acme"}}},
+		{name: "labeled provider", label: "eu", scope: Scope{IncludePaths: []string{"eu/Acme"}}, wantListed: true, wantWhole: true},
+		{name: "group of another provider", label: "eu", scope: Scope{IncludePaths: []string{"us/Acme"}}, wantListed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listed, whole := newScopeFilter(tt.scope).rootGroup(&provider{label: tt.label}, root)
+			if listed != tt.wantListed || whole != tt.wantWhole {
+				t.Errorf("rootGroup() = %v, %v, want %v, %v", listed, whole, tt.wantListed, tt.wantWhole)
+			}
+		})
+	}
+}
+
+func TestScopeDescendants(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope Scope
+		// rootIncluded is whether the root group is in scope, noExternalIDs whether groups are not selected by ID.
+		rootIncluded  bool
+		noExternalIDs bool
+		want          []string
+	}{
+		{name: "whole root group", rootIncluded: true, want: []string{"11", "12", "13", "14"}},
+		{name: "root group out of scope", want: nil},
+		{name: "included by path", scope: Scope{IncludePaths: []string{"Acme/Sales"}}, want: []string{"11", "12", "14"}},
+		{name: "included by deep path", scope: Scope{IncludePaths: []string{"Acme/Sales/East/North"}}, want: []string{"14"}},
+		{name: "path from a descendant", scope: Scope{IncludePaths: []string{"Sales/East"}}, want: nil},
+		{name: "included by ID", scope: Scope{IncludeGroups: []string{"12"}}, want: []string{"12", "14"}},
+		{name: "included by external ID", scope: Scope{IncludeGroups: []string{"sales-ext"}}, want: []string{"11", "12", "14"}},
+		{name: "included by external ID at depth", scope: Scope{IncludeGroups: []string{"east-ext"}}, want: []string{"12", "14"}},
+		{name: "external IDs not fetched", scope: Scope{IncludeGroups: []string{"east-ext"}}, noExternalIDs: true, want: nil},
+		{name: "excluded by path", scope: Scope{ExcludePaths: []string{"Acme/Sales/East"}}, rootIncluded: true, want: []string{"11", "13"}},
+		{name: "excluded by external ID at depth", scope: Scope{ExcludeGroups: []string{"east-ext"}}, rootIncluded: true, want: []string{"11", "13"}},
+		{
+			name:  "included under an excluded group",
+			scope: Scope{IncludePaths: []string{"Acme/Sales/East"}, ExcludeGroups: []string{"sales-ext"}},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			externalIDs := scopeExternalIDs
+			if tt.noExternalIDs {
+				externalIDs = nil
+			}
+
+			got := newScopeFilter(tt.scope).descendants(&provider{}, scopeHierarchy, "Acme", tt.rootIncluded, externalIDs)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("descendants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeMember(t *testing.T) {
+	standIn := newGalileoStandIn()
+	standIn.addGroup("10", "", "Acme")
+	standIn.addAccount("1001", "10", "jane@acme.example").product = "100"
+	standIn.addAccount("1002", "10", "jim@acme.example").product = "200"
+	standIn.addAccount("1003", "10", "joe@acme.example")
+
+	// members without product and unknown members are kept, the failure is handled when their customer is fetched
+	tests := []struct {
+		name  string
+		scope Scope
+		want  []string
+	}{
+		{name: "no products selected", want: []string{"1001", "1002", "1003", "9999"}},
+		{name: "products included", scope: Scope{IncludeProducts: []string{"100"}}, want: []string{"1001", "1003", "9999"}},
+		{name: "products excluded", scope: Scope{ExcludeProducts: []string{"100"}}, want: []string{"1002", "1003", "9999"}},
+		{
+			name:  "exclusion wins",
+			scope: Scope{IncludeProducts: []string{"100", "200"}, ExcludeProducts: []string{"100"}},
+			want:  []string{"1002", "1003", "9999"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newStandInConnector(t, standIn, &Options{Scope: tt.scope})
+			p := g.providers.list[0]
+
+			var got []string
+			for _, accID := range []string{"1001", "1002", "1003", "9999"} {
+				if g.scope.member(context.Background(), p, accID) {
+					got = append(got, accID)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("members in scope = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeGroupParents(t *testing.T) {
+	standIn := newGalileoStandIn()
+	standIn.addGroup("10", "", "Acme")
+	standIn.addGroup("11", "10", "Sales").ExternalID = "sales-ext"
+	standIn.addGroup("12", "11", "East").ExternalID = "east-ext"
+	standIn.addGroup("13", "10", "Marketing")
+	standIn.addGroup("14", "12", "North")
+
+	// parents are given as the parent resource of every synced group, empty for groups synced without parent
+	tests := []struct {
+		name  string
+		scope Scope
+		want  map[string]string
+	}{
+		{
+			name: "no scope",
+			want: map[string]string{"10": "", "11": "10", "12": "11", "13": "10", "14": "12"},
+		},
+		{
+			name:  "descendant of a root group out of scope",
+			scope: Scope{IncludePaths: []string{"Acme/Sales"}},
+			want:  map[string]string{"11": "", "12": "11", "14": "12"},
+		},
+		{
+			name:  "descendant selected by external ID at depth",
+			scope: Scope{IncludeGroups: []string{"east-ext"}},
+			want:  map[string]string{"12": "", "14": "12"},
+		},
+		{
+			name:  "excluded subtree",
+			scope: Scope{ExcludeGroups: []string{"sales-ext"}},
+			want:  map[string]string{"10": "", "13": "10"},
+		},
+		{
+			name:  "included under an excluded group",
+			scope: Scope{IncludePaths: []string{"Acme", "Acme/Sales/East/North"}, ExcludePaths: []string{"Acme/Sales/East"}},
+			want:  map[string]string{"10": "", "11": "10", "13": "10"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newStandInConnector(t, standIn, &Options{Scope: tt.scope})
+
+			got := make(map[string]string)
+			for _, group := range listAll(t, resourceSyncer(t, g, groupResourceType), nil) {
+				got[group.Id.Resource] = group.GetParentResourceId().GetResource()
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("group parents = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+)
+
+// Prefixes of the cached responses and of the state of a sync in its session.
+const (
+	sessionResponsesPrefix = "galileo-responses"
+	sessionStatePrefix     = "galileo-state"
+)
+
+// syncState holds the state shared by all the pages of a sync. It is kept in the session store of the sync when the SDK
+// provides one, so it survives across the invocations of the connector within the sync, and in memory otherwise.
+type syncState struct {
+	store galileo.CacheStore
+
+	// mu serializes the updates of the counts read and written back by add.
+	mu sync.Mutex
+}
+
+func newSyncState(ss sessions.SessionStore) *syncState {
+	store := galileo.NewMemoryCacheStore(0)
+	if ss != nil {
+		store = galileo.NewSessionCacheStore(ss, sessionStatePrefix, store)
+	}
+
+	return &syncState{store: store}
+}
+
+// Reset drops the state of the previous sync kept in memory. It is called at the start of every sync.
+func (s *syncState) Reset(ctx context.Context) error {
+	return s.store.Clear(ctx)
+}
+
+// get reads the value of the key into v, it returns false if the sync has no value for the key.
+func (s *syncState) get(ctx context.Context, key string, v interface{}) (bool, error) {
+	data, ok, err := s.store.Get(ctx, key)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	return true, json.Unmarshal(data, v)
+}
+
+func (s *syncState) set(ctx context.Context, key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Set(ctx, key, data)
+}
+
+// add records the member in the set of the sync and returns the number of members of the set.
+// A member added again is counted once, so pages listed again do not change the count.
+func (s *syncState) add(ctx context.Context, set, member string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int
+	_, err := s.get(ctx, set, &count)
+	if err != nil {
+		return 0, err
+	}
+
+	var added bool
+	ok, err := s.get(ctx, set+"/"+member, &added)
+	if err != nil || ok {
+		return count, err
+	}
+
+	err = s.set(ctx, set+"/"+member, true)
+	if err != nil {
+		return 0, err
+	}
+
+	count++
+
+	return count, s.set(ctx, set, count)
+}
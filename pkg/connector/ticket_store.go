@@ -0,0 +1,212 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ticketRecord is an internal ticket for a Galileo-side change that needs the approval of the program manager.
+// It is the JSON document written to the ticket store, whose status is updated once the change is handled.
+type ticketRecord struct {
+	ID               string          `json:"id"`
+	Status           string          `json:"status"`
+	RequestType      string          `json:"request_type"`
+	Summary          string          `json:"summary"`
+	Description      string          `json:"description,omitempty"`
+	Account          string          `json:"account"`
+	CurrentProduct   string          `json:"current_product,omitempty"`
+	RequestedProduct string          `json:"requested_product,omitempty"`
+	LimitType        string          `json:"limit_type,omitempty"`
+	RequestedLimit   float64         `json:"requested_limit,omitempty"`
+	Justification    string          `json:"justification,omitempty"`
+	Grant            *grantReference `json:"grant,omitempty"`
+	RequestedFor     string          `json:"requested_for,omitempty"`
+	Labels           []string        `json:"labels,omitempty"`
+	URL              string          `json:"url,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	CompletedAt      *time.Time      `json:"completed_at,omitempty"`
+}
+
+// ticketStore records the internal tickets and returns their current status.
+type ticketStore interface {
+	create(ctx context.Context, record *ticketRecord) (*ticketRecord, error)
+	get(ctx context.Context, id string) (*ticketRecord, error)
+}
+
+// fileTicketStore writes every ticket to a JSON file of a local directory.
+type fileTicketStore struct {
+	dir string
+}
+
+func newFileTicketStore(dir string) (*fileTicketStore, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ticket store directory: %w", err)
+	}
+
+	return &fileTicketStore{dir: dir}, nil
+}
+
+func (s *fileTicketStore) path(id string) (string, error) {
+	// IDs are generated by the connector, but are also given by callers when polling
+	if id == "" || filepath.Base(id) != id || id == "." || id == ".." {
+		return "", status.Errorf(codes.InvalidArgument, "galileo-ft-connector: invalid ticket ID %q", id)
+	}
+
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func (s *fileTicketStore) create(_ context.Context, record *ticketRecord) (*ticketRecord, error) {
+	path, err := s.path(record.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ticket: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ticket file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write ticket file: %w", err)
+	}
+
+	return record, nil
+}
+
+func (s *fileTicketStore) get(_ context.Context, id string) (*ticketRecord, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, status.Errorf(codes.NotFound, "galileo-ft-connector: ticket %s not found", id)
+		}
+
+		return nil, fmt.Errorf("failed to read ticket file: %w", err)
+	}
+
+	var record ticketRecord
+	err = json.Unmarshal(data, &record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ticket file: %w", err)
+	}
+
+	return &record, nil
+}
+
+// webhookTicketStore posts every ticket to a webhook, and gets its status from the webhook URL followed by the ticket ID.
+// The webhook may answer with the ticket as recorded on its side, e.g. with its own ID and URL.
+type webhookTicketStore struct {
+	client *uhttp.BaseHttpClient
+	url    *url.URL
+	token  string
+}
+
+func newWebhookTicketStore(ctx context.Context, rawURL, token string) (*webhookTicketStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid ticket webhook URL %q", rawURL)
+	}
+
+	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ticket webhook client: %w", err)
+	}
+
+	client, err := uhttp.NewBaseHttpClientWithContext(ctx, httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ticket webhook client: %w", err)
+	}
+
+	return &webhookTicketStore{
+		client: client,
+		url:    u,
+		token:  token,
+	}, nil
+}
+
+func (s *webhookTicketStore) do(ctx context.Context, method string, u *url.URL, body *ticketRecord) (*ticketRecord, error) {
+	options := []uhttp.RequestOption{uhttp.WithAcceptJSONHeader(), uhttp.WithNoCache()}
+	if body != nil {
+		options = append(options, uhttp.WithJSONBody(body))
+	}
+
+	if s.token != "" {
+		options = append(options, uhttp.WithHeader("Authorization", "Bearer "+s.token))
+	}
+
+	req, err := s.client.NewRequest(ctx, method, u, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ticket webhook request: %w", err)
+	}
+
+	var record ticketRecord
+	resp, err := s.client.Do(req, func(resp *uhttp.WrapperResponse) error {
+		// the webhook may not answer with the ticket
+		if len(resp.Body) == 0 {
+			return nil
+		}
+
+		return json.Unmarshal(resp.Body, &record)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ticket webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if record.ID == "" {
+		return nil, nil
+	}
+
+	return &record, nil
+}
+
+func (s *webhookTicketStore) create(ctx context.Context, record *ticketRecord) (*ticketRecord, error) {
+	created, err := s.do(ctx, http.MethodPost, s.url, record)
+	if err != nil {
+		return nil, err
+	}
+
+	if created == nil {
+		return record, nil
+	}
+
+	return created, nil
+}
+
+func (s *webhookTicketStore) get(ctx context.Context, id string) (*ticketRecord, error) {
+	record, err := s.do(ctx, http.MethodGet, s.url.JoinPath(id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if record == nil {
+		return nil, status.Errorf(codes.NotFound, "galileo-ft-connector: ticket %s not found", id)
+	}
+
+	return record, nil
+}
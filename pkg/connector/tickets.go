@@ -0,0 +1,391 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/ticket"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Program change tickets are raised for changes that the Galileo program manager must approve,
+// e.g. product upgrades or limit exceptions, and are recorded in the configured ticket store.
+const (
+	ticketSchemaID = "galileo_program_change"
+
+	ticketStatusOpen      = "open"
+	ticketStatusApproved  = "approved"
+	ticketStatusRejected  = "rejected"
+	ticketStatusCompleted = "completed"
+
+	ticketTypeProductUpgrade = "product_upgrade"
+	ticketTypeLimitException = "limit_exception"
+
+	ticketFieldAccount          = "account"
+	ticketFieldCurrentProduct   = "current_product"
+	ticketFieldRequestedProduct = "requested_product"
+	ticketFieldLimitType        = "limit_type"
+	ticketFieldRequestedLimit   = "requested_limit"
+	ticketFieldJustification    = "justification"
+	ticketFieldGrant            = "grant"
+)
+
+var ticketStatuses = map[string]string{
+	ticketStatusOpen:      "Open",
+	ticketStatusApproved:  "Approved",
+	ticketStatusRejected:  "Rejected",
+	ticketStatusCompleted: "Completed",
+}
+
+var ticketTypes = map[string]string{
+	ticketTypeProductUpgrade: "Product upgrade",
+	ticketTypeLimitException: "Limit exception",
+}
+
+// ticketSchema is the only schema of the connector tickets.
+var ticketSchema = v2.TicketSchema_builder{
+	Id:          ticketSchemaID,
+	DisplayName: "Galileo program change",
+	Types: []*v2.TicketType{
+		ticketType(ticketTypeProductUpgrade),
+		ticketType(ticketTypeLimitException),
+	},
+	Statuses: []*v2.TicketStatus{
+		ticketStatus(ticketStatusOpen),
+		ticketStatus(ticketStatusApproved),
+		ticketStatus(ticketStatusRejected),
+		ticketStatus(ticketStatusCompleted),
+	},
+	CustomFields: map[string]*v2.TicketCustomField{
+		ticketFieldAccount:          ticket.StringFieldSchema(ticketFieldAccount, "Account", true),
+		ticketFieldCurrentProduct:   ticket.StringFieldSchema(ticketFieldCurrentProduct, "Current product", false),
+		ticketFieldRequestedProduct: ticket.StringFieldSchema(ticketFieldRequestedProduct, "Requested product", false),
+		ticketFieldLimitType:        ticket.StringFieldSchema(ticketFieldLimitType, "Limit type", false),
+		ticketFieldRequestedLimit:   ticket.NumberFieldSchema(ticketFieldRequestedLimit, "Requested limit", false),
+		ticketFieldJustification:    ticket.StringFieldSchema(ticketFieldJustification, "Justification", false),
+		ticketFieldGrant:            ticket.StringFieldSchema(ticketFieldGrant, "Baton grant", false),
+	},
+}.Build()
+
+// ticketStatus returns the ticket status with the given ID, stores may set statuses unknown to the schema.
+func ticketStatus(id string) *v2.TicketStatus {
+	name, ok := ticketStatuses[id]
+	if !ok {
+		name = id
+	}
+
+	return v2.TicketStatus_builder{Id: id, DisplayName: name}.Build()
+}
+
+func ticketType(id string) *v2.TicketType {
+	name, ok := ticketTypes[id]
+	if !ok {
+		name = id
+	}
+
+	return v2.TicketType_builder{Id: id, DisplayName: name}.Build()
+}
+
+// grantableEntitlements are the slugs of the entitlements granted by the connector, by resource type.
+var grantableEntitlements = map[string]map[string]bool{
+	groupResourceType.Id:        {GroupMembership: true, GroupPrimaryContact: true},
+	businessResourceType.Id:     {GroupMembership: true, GroupPrimaryContact: true},
+	userResourceType.Id:         {PrimaryAccount: true},
+	verificationResourceType.Id: {KYCVerified: true},
+}
+
+// grantReference is the Baton grant a ticket is raised for. It is given in the grant field as the grant ID,
+// the entitlement ID (resource type, resource ID and entitlement slug) followed by the principal type and ID,
+// separated by colons.
+type grantReference struct {
+	ResourceType  string `json:"resource_type"`
+	Resource      string `json:"resource"`
+	Entitlement   string `json:"entitlement"`
+	PrincipalType string `json:"principal_type"`
+	Principal     string `json:"principal"`
+}
+
+// parseGrantReference parses a grant ID of an entitlement of the connector granted to a user.
+func parseGrantReference(id string) (*grantReference, error) {
+	parts := strings.Split(id, ":")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("grant ID %q is not an entitlement ID followed by a principal type and ID", id)
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("grant ID %q has an empty part", id)
+		}
+	}
+
+	ref := &grantReference{
+		ResourceType:  parts[0],
+		Resource:      parts[1],
+		Entitlement:   parts[2],
+		PrincipalType: parts[3],
+		Principal:     parts[4],
+	}
+
+	if !grantableEntitlements[ref.ResourceType][ref.Entitlement] {
+		return nil, fmt.Errorf("%s of %s is not an entitlement of the connector", ref.Entitlement, ref.ResourceType)
+	}
+
+	if ref.PrincipalType != userResourceType.Id {
+		return nil, fmt.Errorf("entitlements are only granted to users, got %s", ref.PrincipalType)
+	}
+
+	return ref, nil
+}
+
+// String returns the grant ID of the reference.
+func (r *grantReference) String() string {
+	return strings.Join([]string{r.ResourceType, r.Resource, r.Entitlement, r.PrincipalType, r.Principal}, ":")
+}
+
+func (g *Galileo) ticketStore() (ticketStore, error) {
+	if g.tickets == nil {
+		return nil, status.Error(codes.FailedPrecondition, "galileo-ft-connector: no ticket store configured")
+	}
+
+	return g.tickets, nil
+}
+
+func (g *Galileo) ListTicketSchemas(_ context.Context, _ *pagination.Token) ([]*v2.TicketSchema, string, annotations.Annotations, error) {
+	return []*v2.TicketSchema{ticketSchema}, "", nil, nil
+}
+
+func (g *Galileo) GetTicketSchema(_ context.Context, schemaID string) (*v2.TicketSchema, annotations.Annotations, error) {
+	if schemaID != ticketSchemaID {
+		return nil, nil, status.Errorf(codes.NotFound, "galileo-ft-connector: unknown ticket schema %s", schemaID)
+	}
+
+	return ticketSchema, nil, nil
+}
+
+// CreateTicket records a program change ticket of the type given in the ticket,
+// linked to the Baton grant given in its grant field.
+func (g *Galileo) CreateTicket(ctx context.Context, t *v2.Ticket, schema *v2.TicketSchema) (*v2.Ticket, annotations.Annotations, error) {
+	store, err := g.ticketStore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if schema != nil && schema.GetId() != ticketSchemaID {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "galileo-ft-connector: unknown ticket schema %s", schema.GetId())
+	}
+
+	valid, err := ticket.ValidateTicket(ctx, ticketSchema, t)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "galileo-ft-connector: invalid ticket: %s", err)
+	}
+
+	if !valid {
+		return nil, nil, status.Error(codes.InvalidArgument, "galileo-ft-connector: invalid ticket")
+	}
+
+	requestType := t.GetType().GetId()
+	if _, ok := ticketTypes[requestType]; !ok {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "galileo-ft-connector: invalid ticket type %q", requestType)
+	}
+
+	var grantRef *grantReference
+	if grantID := customFieldString(t, ticketFieldGrant); grantID != "" {
+		grantRef, err = parseGrantReference(grantID)
+		if err != nil {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "galileo-ft-connector: invalid grant: %s", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	record := &ticketRecord{
+		ID:               uuid.NewString(),
+		Status:           ticketStatusOpen,
+		Summary:          t.GetDisplayName(),
+		Description:      t.GetDescription(),
+		RequestType:      requestType,
+		Account:          customFieldString(t, ticketFieldAccount),
+		CurrentProduct:   customFieldString(t, ticketFieldCurrentProduct),
+		RequestedProduct: customFieldString(t, ticketFieldRequestedProduct),
+		LimitType:        customFieldString(t, ticketFieldLimitType),
+		Justification:    customFieldString(t, ticketFieldJustification),
+		Grant:            grantRef,
+		Labels:           t.GetLabels(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+
+	if t.GetStatus().GetId() != "" {
+		record.Status = t.GetStatus().GetId()
+	}
+
+	if limit, err := ticket.GetNumberValue(t.GetCustomFields()[ticketFieldRequestedLimit]); err == nil {
+		record.RequestedLimit = float64(limit)
+	}
+
+	if rid := t.GetRequestedFor().GetId(); rid != nil {
+		record.RequestedFor = rid.GetResourceType() + ":" + rid.GetResource()
+
+		// the grant is raised for the user the ticket is requested for
+		if grantRef != nil && (grantRef.PrincipalType != rid.GetResourceType() || grantRef.Principal != rid.GetResource()) {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "galileo-ft-connector: grant %s is not granted to %s", grantRef, record.RequestedFor)
+		}
+	}
+
+	created, err := store.create(ctx, record)
+	if err != nil {
+		return nil, nil, fmt.Errorf("galileo-ft-connector: failed to create ticket: %w", err)
+	}
+
+	fields := []zap.Field{
+		zap.String("ticket_id", created.ID),
+		zap.String("request_type", created.RequestType),
+		zap.String("account", created.Account),
+	}
+	if created.Grant != nil {
+		fields = append(fields, zap.Stringer("grant", created.Grant))
+	}
+
+	ctxzap.Extract(ctx).Info("galileo-ft-connector: created program change ticket", fields...)
+
+	return recordTicket(created), nil, nil
+}
+
+// GetTicket returns the ticket with its current status in the ticket store.
+func (g *Galileo) GetTicket(ctx context.Context, ticketID string) (*v2.Ticket, annotations.Annotations, error) {
+	store, err := g.ticketStore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	record, err := store.get(ctx, ticketID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("galileo-ft-connector: failed to get ticket: %w", err)
+	}
+
+	return recordTicket(record), nil, nil
+}
+
+func (g *Galileo) BulkCreateTickets(ctx context.Context, request *v2.TicketsServiceBulkCreateTicketsRequest) (*v2.TicketsServiceBulkCreateTicketsResponse, error) {
+	var rv []*v2.TicketsServiceCreateTicketResponse
+	for _, req := range request.GetTicketRequests() {
+		body := req.GetRequest()
+		t := v2.Ticket_builder{
+			DisplayName:  body.GetDisplayName(),
+			Description:  body.GetDescription(),
+			Status:       body.GetStatus(),
+			Type:         body.GetType(),
+			Labels:       body.GetLabels(),
+			CustomFields: body.GetCustomFields(),
+			RequestedFor: body.GetRequestedFor(),
+		}.Build()
+
+		created, annos, err := g.CreateTicket(ctx, t, req.GetSchema())
+		resp := v2.TicketsServiceCreateTicketResponse_builder{
+			Ticket:      created,
+			Annotations: annos,
+		}.Build()
+		if err != nil {
+			resp.SetError(err.Error())
+		}
+
+		rv = append(rv, resp)
+	}
+
+	return v2.TicketsServiceBulkCreateTicketsResponse_builder{Tickets: rv}.Build(), nil
+}
+
+func (g *Galileo) BulkGetTickets(ctx context.Context, request *v2.TicketsServiceBulkGetTicketsRequest) (*v2.TicketsServiceBulkGetTicketsResponse, error) {
+	var rv []*v2.TicketsServiceGetTicketResponse
+	for _, req := range request.GetTicketRequests() {
+		t, annos, err := g.GetTicket(ctx, req.GetId())
+		resp := v2.TicketsServiceGetTicketResponse_builder{
+			Ticket:      t,
+			Annotations: annos,
+		}.Build()
+		if err != nil {
+			resp.SetError(err.Error())
+		}
+
+		rv = append(rv, resp)
+	}
+
+	return v2.TicketsServiceBulkGetTicketsResponse_builder{Tickets: rv}.Build(), nil
+}
+
+// customFieldString returns the value of a string custom field of the ticket, or an empty string if it is not set.
+func customFieldString(t *v2.Ticket, id string) string {
+	value, err := ticket.GetCustomFieldValue(t.GetCustomFields()[id])
+	if err != nil {
+		return ""
+	}
+
+	s, _ := value.(string)
+
+	return s
+}
+
+// recordTicket returns the Baton ticket of a ticket record.
+func recordTicket(record *ticketRecord) *v2.Ticket {
+	fields := map[string]*v2.TicketCustomField{
+		ticketFieldAccount: ticket.StringField(ticketFieldAccount, record.Account),
+	}
+
+	optional := map[string]string{
+		ticketFieldCurrentProduct:   record.CurrentProduct,
+		ticketFieldRequestedProduct: record.RequestedProduct,
+		ticketFieldLimitType:        record.LimitType,
+		ticketFieldJustification:    record.Justification,
+	}
+	if record.Grant != nil {
+		optional[ticketFieldGrant] = record.Grant.String()
+	}
+
+	for id, value := range optional {
+		if value != "" {
+			fields[id] = ticket.StringField(id, value)
+		}
+	}
+
+	if record.RequestedLimit != 0 {
+		fields[ticketFieldRequestedLimit] = ticket.NumberField(ticketFieldRequestedLimit, float32(record.RequestedLimit))
+	}
+
+	t := v2.Ticket_builder{
+		Id:           record.ID,
+		DisplayName:  record.Summary,
+		Description:  record.Description,
+		Status:       ticketStatus(record.Status),
+		Type:         ticketType(record.RequestType),
+		Labels:       record.Labels,
+		Url:          record.URL,
+		CustomFields: fields,
+		CreatedAt:    timestamppb.New(record.CreatedAt),
+		UpdatedAt:    timestamppb.New(record.UpdatedAt),
+	}.Build()
+
+	if resourceType, resourceID, ok := strings.Cut(record.RequestedFor, ":"); ok {
+		t.SetRequestedFor(v2.Resource_builder{
+			Id: v2.ResourceId_builder{ResourceType: resourceType, Resource: resourceID}.Build(),
+		}.Build())
+	}
+
+	// stores may only update the status of handled tickets
+	switch {
+	case record.CompletedAt != nil:
+		t.SetCompletedAt(timestamppb.New(*record.CompletedAt))
+	case record.Status == ticketStatusCompleted || record.Status == ticketStatusRejected:
+		t.SetCompletedAt(timestamppb.New(record.UpdatedAt))
+	}
+
+	return t
+}
@@ -0,0 +1,213 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/ticket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ticketWebhook is a stand-in of a ticket webhook, recording the posted tickets under its own IDs.
+type ticketWebhook struct {
+	mu      sync.Mutex
+	tickets map[string]*ticketRecord
+	authz   []string
+}
+
+func (h *ticketWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.authz = append(h.authz, r.Header.Get("Authorization"))
+
+	switch r.Method {
+	case http.MethodPost:
+		var record ticketRecord
+		if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		record.ID = "PM-" + record.ID
+		record.URL = "https://tickets.example.com/" + record.ID
+		h.tickets[record.ID] = &record
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&record)
+	case http.MethodGet:
+		record, ok := h.tickets[strings.TrimPrefix(r.URL.Path, "/tickets/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(record)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTicketWebhookConnector(t *testing.T) (*Galileo, *ticketWebhook) {
+	t.Helper()
+
+	webhook := &ticketWebhook{tickets: make(map[string]*ticketRecord)}
+	server := httptest.NewServer(webhook)
+	t.Cleanup(server.Close)
+
+	store, err := newWebhookTicketStore(context.Background(), server.URL+"/tickets", "secret")
+	if err != nil {
+		t.Fatalf("newWebhookTicketStore() error = %v", err)
+	}
+
+	return &Galileo{tickets: store}, webhook
+}
+
+func programChangeTicket(ticketType string, fields ...*v2.TicketCustomField) *v2.Ticket {
+	customFields := map[string]*v2.TicketCustomField{
+		ticketFieldAccount: ticket.StringField(ticketFieldAccount, "12345"),
+	}
+	for _, f := range fields {
+		customFields[f.GetId()] = f
+	}
+
+	return v2.Ticket_builder{
+		DisplayName:  "Upgrade to the premium product",
+		Type:         v2.TicketType_builder{Id: ticketType}.Build(),
+		CustomFields: customFields,
+		RequestedFor: v2.Resource_builder{
+			Id: v2.ResourceId_builder{ResourceType: userResourceType.Id, Resource: "12345"}.Build(),
+		}.Build(),
+	}.Build()
+}
+
+func TestCreateTicketWebhook(t *testing.T) {
+	ctx := context.Background()
+	g, webhook := newTicketWebhookConnector(t)
+
+	created, _, err := g.CreateTicket(ctx, programChangeTicket(
+		ticketTypeProductUpgrade,
+		ticket.StringField(ticketFieldRequestedProduct, "premium"),
+		ticket.StringField(ticketFieldGrant, "group:42:member:user:12345"),
+	), ticketSchema)
+	if err != nil {
+		t.Fatalf("CreateTicket() error = %v", err)
+	}
+
+	if !strings.HasPrefix(created.GetId(), "PM-") || created.GetUrl() == "" {
+		t.Errorf("ticket %q (%s) is not the ticket recorded by the webhook", created.GetId(), created.GetUrl())
+	}
+
+	if created.GetType().GetId() != ticketTypeProductUpgrade {
+		t.Errorf("ticket type = %q, want %q", created.GetType().GetId(), ticketTypeProductUpgrade)
+	}
+
+	webhook.mu.Lock()
+	record := webhook.tickets[created.GetId()]
+	webhook.mu.Unlock()
+
+	if record == nil {
+		t.Fatalf("ticket %s was not posted to the webhook", created.GetId())
+	}
+
+	if record.RequestType != ticketTypeProductUpgrade {
+		t.Errorf("posted request type = %q, want %q", record.RequestType, ticketTypeProductUpgrade)
+	}
+
+	want := &grantReference{ResourceType: "group", Resource: "42", Entitlement: GroupMembership, PrincipalType: "user", Principal: "12345"}
+	if record.Grant == nil || *record.Grant != *want {
+		t.Errorf("posted grant = %+v, want %+v", record.Grant, want)
+	}
+
+	// the status is read back from the webhook
+	webhook.mu.Lock()
+	record.Status = ticketStatusApproved
+	webhook.mu.Unlock()
+
+	got, _, err := g.GetTicket(ctx, created.GetId())
+	if err != nil {
+		t.Fatalf("GetTicket() error = %v", err)
+	}
+
+	if got.GetStatus().GetId() != ticketStatusApproved {
+		t.Errorf("ticket status = %q, want %q", got.GetStatus().GetId(), ticketStatusApproved)
+	}
+
+	grantID, err := ticket.GetStringValue(got.GetCustomFields()[ticketFieldGrant])
+	if err != nil || grantID != "group:42:member:user:12345" {
+		t.Errorf("ticket grant = %q (%v), want group:42:member:user:12345", grantID, err)
+	}
+
+	webhook.mu.Lock()
+	defer webhook.mu.Unlock()
+
+	for i, authz := range webhook.authz {
+		if authz != "Bearer secret" {
+			t.Errorf("request %d sent Authorization %q", i, authz)
+		}
+	}
+}
+
+func TestCreateTicketInvalid(t *testing.T) {
+	ctx := context.Background()
+	g, webhook := newTicketWebhookConnector(t)
+
+	tests := []struct {
+		name   string
+		ticket *v2.Ticket
+	}{
+		{
+			name:   "unknown type",
+			ticket: programChangeTicket("card_upgrade"),
+		},
+		{
+			name:   "no type",
+			ticket: programChangeTicket(""),
+		},
+		{
+			name:   "malformed grant",
+			ticket: programChangeTicket(ticketTypeLimitException, ticket.StringField(ticketFieldGrant, "group:42:member")),
+		},
+		{
+			name:   "unknown entitlement",
+			ticket: programChangeTicket(ticketTypeLimitException, ticket.StringField(ticketFieldGrant, "group:42:admin:user:12345")),
+		},
+		{
+			name:   "grant of another user",
+			ticket: programChangeTicket(ticketTypeLimitException, ticket.StringField(ticketFieldGrant, "group:42:member:user:67890")),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := g.CreateTicket(ctx, tt.ticket, ticketSchema)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("CreateTicket() error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+
+	webhook.mu.Lock()
+	defer webhook.mu.Unlock()
+
+	if len(webhook.tickets) != 0 {
+		t.Errorf("invalid tickets were posted to the webhook: %v", webhook.tickets)
+	}
+}
+
+func TestGetTicketNotFound(t *testing.T) {
+	g, _ := newTicketWebhookConnector(t)
+
+	_, _, err := g.GetTicket(context.Background(), "PM-unknown")
+	if err == nil {
+		t.Fatal("GetTicket() expected an error")
+	}
+}
@@ -0,0 +1,104 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errNoCustomer = errors.New("account has no customer profile")
+
+// placeholderStatus details the status of the placeholder users of the accounts that failed to sync.
+const placeholderStatus = "placeholder: the customer of the account could not be fetched"
+
+// isAccountError reports whether the error is specific to an account, e.g. an unknown account or an account without customer,
+// rather than a transient or configuration error that would affect every account.
+// Requests rejected for lack of authorization are never account errors, whatever their Galileo status.
+func isAccountError(err error) bool {
+	if errors.Is(err, errNoCustomer) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return false
+	}
+
+	var galileoErr *galileo.ErrorResponse
+	return errors.As(err, &galileoErr) && galileoErr.AccountError()
+}
+
+// failedAccountsSet is the set of the accounts failed in the sync, in the state of the sync.
+const failedAccountsSet = "failed-accounts"
+
+// tolerateFailure records an account that could not be synced and returns nil if the sync can go on without it.
+// Only account errors are tolerated, and only up to the configured number of failed accounts per sync.
+// The failed accounts are counted in the state of the sync rather than in its report,
+// so the count goes on when the sync resumes, and an account failing again on a page listed again is counted once.
+func (u *userBuilder) tolerateFailure(ctx context.Context, p *provider, accID string, parentResourceID *v2.ResourceId, err error) error {
+	if u.maxAccountFailures <= 0 || !isAccountError(err) {
+		return err
+	}
+
+	u.progress.accountFailed(p.resourceID(accID), parentResourceID.Resource, err)
+
+	failures, serr := u.state.add(ctx, failedAccountsSet, p.resourceID(accID))
+	if serr != nil {
+		return fmt.Errorf("galileo-ft-connector: failed to count failed account %s: %w", p.resourceID(accID), serr)
+	}
+
+	if failures > u.maxAccountFailures {
+		return fmt.Errorf("galileo-ft-connector: %d accounts failed, more than the %d tolerated: %w", failures, u.maxAccountFailures, err)
+	}
+
+	ctxzap.Extract(ctx).Warn(
+		"galileo-ft-connector: skipping failed account",
+		zap.String("account", p.resourceID(accID)),
+		zap.String("group", parentResourceID.Resource),
+		zap.Error(err),
+	)
+
+	return nil
+}
+
+// placeholderResource creates a user resource with minimal data for an account whose customer could not be fetched.
+// The state of the account is unknown, so placeholders are marked as such in their status details and profile.
+func placeholderResource(p *provider, accID, primaryAccID string, related []string, cause error, parentResource *v2.ResourceId) (*v2.Resource, error) {
+	userProfile := map[string]interface{}{
+		"prn":         accID,
+		"placeholder": true,
+		"sync_error":  cause.Error(),
+	}
+
+	if primaryAccID != "" {
+		userProfile["primary_account"] = primaryAccID
+	}
+
+	if len(related) > 0 {
+		userProfile["related_accounts"] = relatedAccountsValue(related)
+	}
+
+	if p.label != "" {
+		userProfile["provider"] = p.label
+	}
+
+	return rs.NewUserResource(
+		accID,
+		userResourceType,
+		p.resourceID(accID),
+		[]rs.UserTraitOption{
+			rs.WithUserProfile(userProfile),
+			rs.WithDetailedStatus(v2.UserTrait_Status_STATUS_UNSPECIFIED, placeholderStatus),
+			rs.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_HUMAN),
+		},
+		rs.WithParentResourceID(parentResource),
+	)
+}
@@ -0,0 +1,101 @@
+package connector
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+)
+
+func TestTolerateFailure(t *testing.T) {
+	standIn := newGalileoStandIn()
+	standIn.addGroup("10", "", "Acme")
+	standIn.addGroup("20", "", "Globex")
+	standIn.addAccount("1001", "10", "jane@acme.example")
+	standIn.addAccount("1002", "10", "jim@acme.example").status = galileo.StatusAccountNotFound
+	standIn.addAccount("1003", "10", "joe@acme.example").status = galileo.StatusAccountNotFound
+	standIn.addAccount("2001", "20", "john@globex.example")
+	standIn.addAccount("2002", "20", "jack@globex.example").status = galileo.StatusAccountNotFound
+	standIn.addAccount("2003", "20", "jill@globex.example").status = 465
+
+	tests := []struct {
+		name         string
+		maxFailures  int
+		placeholders bool
+		group        string
+		users        []string
+		wantErr      bool
+	}{
+		{name: "under the threshold", maxFailures: 3, group: "10", users: []string{"1001"}},
+		{name: "at the threshold", maxFailures: 2, group: "10", users: []string{"1001"}},
+		{name: "at the threshold with placeholders", maxFailures: 2, placeholders: true, group: "10", users: []string{"1001", "1002", "1003"}},
+		{name: "over the threshold", maxFailures: 1, group: "10", wantErr: true},
+		{name: "not tolerated", group: "10", wantErr: true},
+		{name: "not an account error", maxFailures: 3, group: "20", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newStandInConnector(t, standIn, &Options{MaxAccountFailures: tt.maxFailures, PlaceholderUsers: tt.placeholders})
+			users := resourceSyncer(t, g, userResourceType)
+
+			parentID := &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: tt.group}
+			resources, _, _, err := users.List(context.Background(), parentID, &pagination.Token{})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("List() returned %v, expected an error", resourceIDs(resources))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+
+			if got := resourceIDs(resources); !reflect.DeepEqual(got, tt.users) {
+				t.Errorf("List() = %v, want %v", got, tt.users)
+			}
+		})
+	}
+}
+
+func TestTolerateFailureAcrossInvocations(t *testing.T) {
+	standIn := newGalileoStandIn()
+	standIn.addGroup("10", "", "Acme")
+	standIn.addGroup("20", "", "Globex")
+	standIn.addAccount("1001", "10", "jane@acme.example").status = galileo.StatusAccountNotFound
+	standIn.addAccount("1002", "10", "jim@acme.example").status = galileo.StatusAccountNotFound
+	standIn.addAccount("2001", "20", "john@globex.example").status = galileo.StatusAccountNotFound
+
+	ss := newSessionStandIn()
+	ctx := sessions.SetSyncIDInContext(context.Background(), "sync-1")
+
+	list := func(groupID string) error {
+		t.Helper()
+
+		// every page is listed by another invocation of the connector, which starts without any report
+		g := newStandInConnector(t, standIn, &Options{MaxAccountFailures: 2, SessionStore: ss})
+		parentID := &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: groupID}
+		_, _, _, err := resourceSyncer(t, g, userResourceType).List(ctx, parentID, &pagination.Token{})
+
+		return err
+	}
+
+	if err := list("10"); err != nil {
+		t.Fatalf("List() of the failures at the threshold error = %v", err)
+	}
+
+	// the same accounts failing again on a page listed again are not counted again
+	if err := list("10"); err != nil {
+		t.Fatalf("List() of the page listed again error = %v", err)
+	}
+
+	if err := list("20"); err == nil {
+		t.Error("List() of the failures over the threshold of the sync succeeded")
+	}
+}
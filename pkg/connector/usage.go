@@ -0,0 +1,180 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+)
+
+const (
+	usagePageSize = 100
+	usageTopMCCs  = 3
+)
+
+var errUsageBudget = errors.New("usage call budget of the sync exhausted")
+
+// UsageOptions enables the usage phase, summarizing the recent card activity of every account on its user.
+type UsageOptions struct {
+	// Window is how far back transactions and authorizations are summarized, zero disables the usage phase.
+	Window time.Duration
+	// MaxPages bounds the pages of history read per account and endpoint, the summary is marked truncated beyond.
+	MaxPages int
+	// MaxCalls bounds the history calls per sync, accounts synced once it is reached have no summary.
+	MaxCalls int
+	// Location is the time zone of the timestamps and dates of the history of the program, UTC if nil.
+	Location *time.Location
+}
+
+// usageSummary is the card activity of an account over the usage window.
+type usageSummary struct {
+	window         time.Duration
+	transactions   int
+	authorizations int
+	lastUsed       time.Time
+	topMCCs        []string
+	truncated      bool
+}
+
+// profile returns the profile fields of the summary, merged into the user profile.
+func (s *usageSummary) profile() map[string]interface{} {
+	rv := map[string]interface{}{
+		"usage_window_days":    int(s.window.Hours() / 24),
+		"usage_transactions":   s.transactions,
+		"usage_authorizations": s.authorizations,
+		"usage_top_mccs":       strings.Join(s.topMCCs, ","),
+	}
+
+	if !s.lastUsed.IsZero() {
+		rv["usage_last_used"] = s.lastUsed.UTC().Format(time.RFC3339)
+	}
+
+	if s.truncated {
+		rv["usage_truncated"] = true
+	}
+
+	return rv
+}
+
+// usageCollector reads the transaction and authorization history of accounts within the volume limits.
+type usageCollector struct {
+	opts     UsageOptions
+	progress *syncProgress
+}
+
+func newUsageCollector(opts UsageOptions, progress *syncProgress) *usageCollector {
+	if opts.Window <= 0 {
+		return nil
+	}
+
+	return &usageCollector{
+		opts:     opts,
+		progress: progress,
+	}
+}
+
+// summarize returns the usage summary of the account, or errUsageBudget once the sync used all its history calls.
+func (c *usageCollector) summarize(ctx context.Context, p *provider, accID string) (*usageSummary, error) {
+	ctx, done := c.progress.startPhase(ctx, phaseUserUsage)
+	defer done()
+
+	location := c.opts.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	// the history is requested by date and returns timestamps in the time zone of the program
+	end := time.Now().In(location)
+	start := end.Add(-c.opts.Window)
+	summary := &usageSummary{window: c.opts.Window}
+	mccs := make(map[string]int)
+
+	record := func(timestamp, mcc string) {
+		if t, err := time.ParseInLocation(galileo.TimestampFormat, timestamp, location); err == nil && t.After(summary.lastUsed) {
+			summary.lastUsed = t
+		}
+
+		if mcc != "" {
+			mccs[mcc]++
+		}
+	}
+
+	err := c.pages(func(pgVars *galileo.PaginationVars) (uint, error) {
+		transactions, pages, err := p.client.ListTransactions(ctx, accID, start, end, pgVars)
+		for _, t := range transactions {
+			summary.transactions++
+			record(t.Timestamp, t.MCC)
+		}
+
+		return pages, err
+	}, &summary.truncated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	err = c.pages(func(pgVars *galileo.PaginationVars) (uint, error) {
+		authorizations, pages, err := p.client.ListAuthorizations(ctx, accID, start, end, pgVars)
+		for _, a := range authorizations {
+			summary.authorizations++
+			record(a.Timestamp, a.MCC)
+		}
+
+		return pages, err
+	}, &summary.truncated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list authorizations: %w", err)
+	}
+
+	summary.topMCCs = topKeys(mccs, usageTopMCCs)
+
+	return summary, nil
+}
+
+// pages calls list for every page of a history, up to the page limit, and sets truncated if pages were left unread.
+func (c *usageCollector) pages(list func(pgVars *galileo.PaginationVars) (uint, error), truncated *bool) error {
+	for page := uint(1); ; page++ {
+		if c.opts.MaxPages > 0 && page > uint(c.opts.MaxPages) {
+			*truncated = true
+			return nil
+		}
+
+		if !c.progress.takeUsageCall(c.opts.MaxCalls) {
+			return errUsageBudget
+		}
+
+		pages, err := list(galileo.NewPaginationVars(page, usagePageSize))
+		if err != nil {
+			return err
+		}
+
+		if page >= pages {
+			return nil
+		}
+	}
+}
+
+// topKeys returns the n keys with the highest counts, ties are ordered by key.
+func topKeys(counts map[string]int, n int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+
+	return keys
+}
@@ -2,25 +2,64 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+const PrimaryAccount = "primary-account"
+
 type userBuilder struct {
-	client       *galileo.Client
-	resourceType *v2.ResourceType
+	providers     *providerSet
+	resourceType  *v2.ResourceType
+	progress      *syncProgress
+	useExternalID bool
+	// maxAccountFailures is the number of failed accounts tolerated per sync, zero fails the sync on the first one.
+	maxAccountFailures int
+	// placeholders emits a user with minimal data for the tolerated failed accounts.
+	placeholders bool
+	scope        *scopeFilter
+	// businesses syncs the members of root groups under their business resource.
+	businesses bool
+	// identityProfile adds the identity verification state of the customer to the user profile.
+	identityProfile bool
+	// kycVerified grants the kyc-verified entitlement to the accounts whose customer passed the identity verification,
+	// their verification state is added to the profile as with identityProfile.
+	kycVerified bool
+	// usage summarizes the recent card activity of the accounts, if enabled.
+	usage *usageCollector
+	// state records the owners of the related accounts synced so far in the sync.
+	state *syncState
 }
 
 func (u *userBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return userResourceType
 }
 
-func userResource(accID string, user *galileo.Customer, parentResource *v2.ResourceId) (*v2.Resource, error) {
+// userResource creates a user resource with the given resource ID for the given account of the provider.
+// For secondary accounts, primaryAccID holds the PRN of the primary account they are related to,
+// for primary accounts, related holds the PRNs of their secondary accounts so that their grants need no further request.
+// The identity verification state of the customer is added to the profile if identity is set.
+// The usage summary of the account, if any, is added to the profile, including the time of the last card use.
+func userResource(
+	p *provider,
+	resourceID, accID, primaryAccID string,
+	related []string,
+	user *galileo.Customer,
+	identity bool,
+	usage *usageSummary,
+	parentResource *v2.ResourceId,
+) (*v2.Resource, error) {
 	userProfile := map[string]interface{}{
 		"first_name":   user.FirstName,
 		"middle_name":  user.MiddleName,
@@ -33,20 +72,57 @@ func userResource(accID string, user *galileo.Customer, parentResource *v2.Resou
 		"country":      user.CountryCode,
 		"home_phone":   user.HomePhone,
 		"mobile_phone": user.MobilePhone,
+		"prn":          accID,
+		"external_id":  user.ExternalID,
+	}
+
+	if primaryAccID != "" {
+		userProfile["primary_account"] = primaryAccID
+	}
+
+	if len(related) > 0 {
+		userProfile["related_accounts"] = relatedAccountsValue(related)
+	}
+
+	if p.label != "" {
+		userProfile["provider"] = p.label
+	}
+
+	if identity {
+		userProfile["cip_status"] = user.CIPStatus
+		userProfile["kyc_result"] = user.KYCResult
+		userProfile["ofac_match"] = user.OFACMatch
+		userProfile["identity_verified"] = user.IdentityVerified()
+	}
+
+	traitOptions := []rs.UserTraitOption{
+		rs.WithEmail(user.Email, true),
+		rs.WithStatus(v2.UserTrait_Status_STATUS_ENABLED),
+		rs.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_HUMAN),
+	}
+
+	if usage != nil {
+		// the last card use is not a login, it is only kept in the profile
+		for k, v := range usage.profile() {
+			userProfile[k] = v
+		}
+	}
+
+	options := []rs.ResourceOption{
+		rs.WithParentResourceID(parentResource),
+	}
+
+	if user.ExternalID != "" {
+		options = append(options, rs.WithExternalID(&v2.ExternalId{Id: user.ExternalID}))
 	}
 
 	fullName := fmt.Sprintf("%s %s", user.FirstName, user.LastName)
 	resource, err := rs.NewUserResource(
 		fullName,
 		userResourceType,
-		accID,
-		[]rs.UserTraitOption{
-			rs.WithUserProfile(userProfile),
-			rs.WithEmail(user.Email, true),
-			rs.WithStatus(v2.UserTrait_Status_STATUS_ENABLED),
-			rs.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_HUMAN),
-		},
-		rs.WithParentResourceID(parentResource),
+		resourceID,
+		append(traitOptions, rs.WithUserProfile(userProfile)),
+		options...,
 	)
 	if err != nil {
 		return nil, err
@@ -55,86 +131,503 @@ func userResource(accID string, user *galileo.Customer, parentResource *v2.Resou
 	return resource, nil
 }
 
-func (u *userBuilder) GetAccountCustomer(ctx context.Context, accID string, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
-	customer, err := u.client.GetCustomer(ctx, accID)
+// accountResource fetches the customer of the account and creates its user resource.
+// The resource is identified by the Galileo external ID of the customer if configured and available,
+// otherwise by the account PRN.
+// Both are namespaced by the provider label.
+// If the customer cannot be fetched and the failure is tolerated, a placeholder or no resource is returned.
+func (u *userBuilder) accountResource(
+	ctx context.Context,
+	p *provider,
+	accID, primaryAccID string,
+	related []string,
+	parentResourceID *v2.ResourceId,
+) (*v2.Resource, error) {
+	customer, err := p.client.GetCustomer(ctx, accID)
+	if err == nil && customer == nil {
+		err = errNoCustomer
+	}
+
 	if err != nil {
-		return nil, fmt.Errorf("galileo-ft-connector: failed to get customer: %w", err)
+		err = fmt.Errorf("galileo-ft-connector: failed to get customer: %w", err)
+		if terr := u.tolerateFailure(ctx, p, accID, parentResourceID, err); terr != nil {
+			return nil, terr
+		}
+
+		if !u.placeholders {
+			return nil, nil
+		}
+
+		ur, err := placeholderResource(p, accID, primaryAccID, related, err, parentResourceID)
+		if err != nil {
+			return nil, fmt.Errorf("galileo-ft-connector: failed to create user resource: %w", err)
+		}
+
+		return ur, nil
+	}
+
+	resourceID := p.resourceID(accID)
+	if u.useExternalID && customer.ExternalID != "" {
+		resourceID = p.resourceID(customer.ExternalID)
+	}
+
+	identity := u.identityProfile || u.kycVerified
+	ur, err := userResource(p, resourceID, accID, primaryAccID, related, customer, identity, u.accountUsage(ctx, p, accID), parentResourceID)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to create user resource: %w", err)
+	}
+
+	return ur, nil
+}
+
+// accountResourceID returns the ID of the user resource of the account, resolved as when the account is listed,
+// so that grants and events reference the synced user without keeping any state between pages.
+// The customer is only fetched if users are identified by their external ID, accounts whose customer
+// cannot be fetched are identified by their PRN as their placeholder.
+func accountResourceID(ctx context.Context, p *provider, accID string, useExternalID bool) (*v2.ResourceId, error) {
+	resourceID := p.resourceID(accID)
+	if useExternalID {
+		customer, err := p.client.GetCustomer(ctx, accID)
+		if err != nil && !isAccountError(err) {
+			return nil, fmt.Errorf("galileo-ft-connector: failed to get customer of account %s: %w", p.resourceID(accID), err)
+		}
+
+		if err == nil && customer != nil && customer.ExternalID != "" {
+			resourceID = p.resourceID(customer.ExternalID)
+		}
 	}
 
-	return userResource(accID, customer, parentResourceID)
+	return rs.NewResourceID(userResourceType, resourceID)
 }
 
-func (u *userBuilder) ListRelatedCustomers(ctx context.Context, accID string, parentResourceID *v2.ResourceId) ([]*v2.Resource, error) {
-	accounts, err := u.client.ListRelatedAccounts(ctx, accID)
+// accountUsage returns the usage summary of the account, or nil if the usage phase is disabled or the summary failed.
+// A failed summary does not fail the sync, the user is synced without it.
+func (u *userBuilder) accountUsage(ctx context.Context, p *provider, accID string) *usageSummary {
+	if u.usage == nil {
+		return nil
+	}
+
+	summary, err := u.usage.summarize(ctx, p, accID)
 	if err != nil {
-		return nil, fmt.Errorf("galileo-ft-connector: failed to list related accounts: %w", err)
+		u.progress.usageSummarized(true)
+
+		l := ctxzap.Extract(ctx)
+		if errors.Is(err, errUsageBudget) {
+			l.Debug("galileo-ft-connector: skipping usage summary", zap.String("account", p.resourceID(accID)), zap.Error(err))
+		} else {
+			l.Warn("galileo-ft-connector: failed to summarize usage", zap.String("account", p.resourceID(accID)), zap.Error(err))
+		}
+
+		return nil
 	}
 
+	u.progress.usageSummarized(false)
+
+	return summary
+}
+
+// relatedAccounts returns the accounts in scope related to the given account.
+// A failure is tolerated as for the customer of the account, the account is then synced without its related accounts.
+func (u *userBuilder) relatedAccounts(ctx context.Context, p *provider, accID string, parentResourceID *v2.ResourceId) ([]galileo.Account, error) {
+	accounts, err := p.client.ListRelatedAccounts(ctx, accID)
+	if err != nil {
+		err = fmt.Errorf("galileo-ft-connector: failed to list related accounts: %w", err)
+		return nil, u.tolerateFailure(ctx, p, accID, parentResourceID, err)
+	}
+
+	rv := make([]galileo.Account, 0, len(accounts))
+	for _, acc := range accounts {
+		// accounts of products out of scope are skipped before their customer is fetched
+		if u.scope.account(p, acc.ProdID) {
+			rv = append(rv, acc)
+		}
+	}
+
+	return rv, nil
+}
+
+// relatedAccountOwner is the group and the primary account a related account of no group is synced under.
+type relatedAccountOwner struct {
+	Group   string `json:"group"`
+	Primary string `json:"primary"`
+}
+
+// claimRelatedAccount records in the state of the sync that the related account is synced under the given owner,
+// unless it was claimed by another owner earlier in the sync, which is returned instead.
+// A page listed again claims its accounts again, so a retried or resumed page emits the same users.
+func (u *userBuilder) claimRelatedAccount(ctx context.Context, p *provider, accID string, owner relatedAccountOwner) (relatedAccountOwner, error) {
+	key := "related-account-owner/" + p.resourceID(accID)
+
+	var current relatedAccountOwner
+	ok, err := u.state.get(ctx, key, &current)
+	if err != nil {
+		return current, fmt.Errorf("galileo-ft-connector: failed to read owner of account %s: %w", p.resourceID(accID), err)
+	}
+
+	if ok {
+		return current, nil
+	}
+
+	err = u.state.set(ctx, key, owner)
+	if err != nil {
+		return current, fmt.Errorf("galileo-ft-connector: failed to record owner of account %s: %w", p.resourceID(accID), err)
+	}
+
+	return owner, nil
+}
+
+// accountConflict reports an account observed in another place of the hierarchy than the one it is synced in.
+// The account is not emitted again, its relationships are kept as grants.
+func (u *userBuilder) accountConflict(ctx context.Context, p *provider, accID string, parentResourceID *v2.ResourceId, reason string) accountIssue {
+	ctxzap.Extract(ctx).Warn(
+		"galileo-ft-connector: conflicting account relationship",
+		zap.String("account", p.resourceID(accID)),
+		zap.String("group", parentResourceID.Resource),
+		zap.String("reason", reason),
+	)
+	u.progress.accountSkipped(p.resourceID(accID), parentResourceID.Resource, reason)
+
+	return accountIssue{Account: p.resourceID(accID), Group: parentResourceID.Resource, Reason: reason}
+}
+
+// ListRelatedCustomers returns user resources for the given accounts related to the given account
+// that are not members of any group and were not synced under another group or primary account yet in the sync,
+// along with the conflicts found.
+// Members of a group are emitted under their group, the relationship is kept as a grant of the primary account.
+func (u *userBuilder) ListRelatedCustomers(
+	ctx context.Context,
+	p *provider,
+	accID string,
+	accounts []galileo.Account,
+	members map[string]bool,
+	parentResourceID *v2.ResourceId,
+) ([]*v2.Resource, []accountIssue, error) {
 	var rv []*v2.Resource
+	var conflicts []accountIssue
 	for _, acc := range accounts {
-		customer, err := u.client.GetCustomer(ctx, acc.ID)
+		if members[acc.ID] {
+			continue
+		}
+
+		groupID, err := p.client.GetAccountGroup(ctx, acc.ID)
 		if err != nil {
-			return nil, fmt.Errorf("galileo-ft-connector: failed to get customer: %w", err)
+			err = fmt.Errorf("galileo-ft-connector: failed to get group of account: %w", err)
+			if terr := u.tolerateFailure(ctx, p, acc.ID, parentResourceID, err); terr != nil {
+				return nil, nil, terr
+			}
+
+			continue
+		}
+
+		if groupID != "" {
+			if p.resourceID(groupID) != parentResourceID.Resource {
+				reason := fmt.Sprintf("account related to %s is a member of group %s", p.resourceID(accID), p.resourceID(groupID))
+				conflicts = append(conflicts, u.accountConflict(ctx, p, acc.ID, parentResourceID, reason))
+			}
+
+			continue
 		}
 
-		ur, err := userResource(acc.ID, customer, parentResourceID)
+		owner := relatedAccountOwner{Group: parentResourceID.Resource, Primary: p.resourceID(accID)}
+		claimed, err := u.claimRelatedAccount(ctx, p, acc.ID, owner)
 		if err != nil {
-			return nil, fmt.Errorf("galileo-ft-connector: failed to create user resource: %w", err)
+			return nil, nil, err
+		}
+
+		if claimed != owner {
+			reason := fmt.Sprintf(
+				"account related to %s is synced under group %s as related to %s",
+				owner.Primary,
+				claimed.Group,
+				claimed.Primary,
+			)
+			conflicts = append(conflicts, u.accountConflict(ctx, p, acc.ID, parentResourceID, reason))
+			continue
+		}
+
+		ur, err := u.accountResource(ctx, p, acc.ID, accID, nil, parentResourceID)
+		if err != nil {
+			return nil, nil, err
 		}
 
-		rv = append(rv, ur)
+		if ur != nil {
+			rv = append(rv, ur)
+		}
 	}
 
-	return rv, nil
+	u.progress.addAccounts(len(rv), len(rv))
+
+	return rv, conflicts, nil
+}
+
+// conflictAnnotations reports every account conflict found on a page in an annotation of the page.
+func conflictAnnotations(conflicts []accountIssue) (annotations.Annotations, error) {
+	var annos annotations.Annotations
+	for _, conflict := range conflicts {
+		st, err := structpb.NewStruct(map[string]interface{}{
+			"account_conflict": map[string]interface{}{
+				"account": conflict.Account,
+				"group":   conflict.Group,
+				"reason":  conflict.Reason,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		annos.Append(st)
+	}
+
+	return annos, nil
 }
 
 // List returns all the users from the database as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
+// Every account is emitted once per sync: members are emitted under their group, and related accounts under the group
+// of the first primary account they are listed with in the sync, unless they are members of a group themselves.
+// Accounts observed elsewhere are reported as conflicts in the annotations of the page.
 func (u *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
 	if parentResourceID == nil {
 		return nil, "", nil, nil
 	}
 
-	group, err := u.client.ListGroupMembers(ctx, parentResourceID.Resource)
+	ctx, done := u.progress.startPhase(ctx, phaseUserList)
+	defer done()
+
+	p, groupID, err := u.providers.parseResourceID(parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to parse group ID: %w", err)
+	}
+
+	group, err := p.client.ListGroupMembers(ctx, groupID)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to list accounts under group %s: %w", parentResourceID.Resource, err)
 	}
 
+	// members are emitted under the group, not as related accounts
+	members := make(map[string]bool, len(group.AccountIDs))
+	for _, accID := range group.AccountIDs {
+		members[accID] = true
+	}
+
 	var rv []*v2.Resource
+	var conflicts []accountIssue
 	for _, accID := range group.AccountIDs {
-		// first get the customer of the parent user
-		parent, err := u.GetAccountCustomer(ctx, accID, parentResourceID)
+		// members of products out of scope are skipped with their related accounts
+		if !u.scope.member(ctx, p, accID) {
+			u.progress.accountSkipped(p.resourceID(accID), parentResourceID.Resource, "product out of scope")
+			continue
+		}
+
+		related, err := u.relatedAccounts(ctx, p, accID, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
 
-		rv = append(rv, parent)
+		// first get the customer of the parent user, along with the accounts related to it
+		relatedIDs := make([]string, 0, len(related))
+		for _, acc := range related {
+			relatedIDs = append(relatedIDs, acc.ID)
+		}
+
+		parent, err := u.accountResource(ctx, p, accID, "", relatedIDs, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		if parent != nil {
+			rv = append(rv, parent)
+			u.progress.addAccounts(1, 0)
+		}
 
 		// then get the related children accounts of the parent user
-		accounts, err := u.ListRelatedCustomers(ctx, accID, parentResourceID)
+		accounts, accountConflicts, err := u.ListRelatedCustomers(ctx, p, accID, related, members, parentResourceID)
 		if err != nil {
 			return nil, "", nil, err
 		}
 
 		rv = append(rv, accounts...)
+		conflicts = append(conflicts, accountConflicts...)
 	}
 
-	return rv, "", nil, nil
+	annos, err := conflictAnnotations(conflicts)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to prepare conflict annotations: %w", err)
+	}
+
+	return rv, "", annos, nil
 }
 
-// Entitlements always returns an empty slice for users.
+// Entitlements returns the primary account entitlement of primary accounts,
+// which is granted to the secondary accounts related to the user's account.
+// Secondary accounts cannot have secondary accounts of their own, so they have no entitlement.
 func (u *userBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	if isSecondaryAccount(resource) {
+		return nil, "", nil, nil
+	}
+
+	var rv []*v2.Entitlement
+
+	assignmentOptions := []ent.EntitlementOption{
+		ent.WithGrantableTo(userResourceType),
+		ent.WithDisplayName(fmt.Sprintf("%s primary account", resource.DisplayName)),
+		ent.WithDescription(fmt.Sprintf("Secondary account related to the primary account of %s", resource.DisplayName)),
+	}
+
+	rv = append(rv, ent.NewAssignmentEntitlement(resource, PrimaryAccount, assignmentOptions...))
+
+	return rv, "", nil, nil
+}
+
+// Grants returns a grant of the primary account entitlement for each account related to the user's account.
+// The related accounts are the ones recorded in the profile when the user was listed.
+// If enabled, the kyc-verified entitlement of the provider is granted to the user when its customer passed the verification.
+func (u *userBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	ctx, done := u.progress.startPhase(ctx, phaseUserGrants)
+	defer done()
+
+	var rv []*v2.Grant
+
+	p, _, err := u.providers.parseAccount(resource)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to parse account: %w", err)
+	}
+
+	for _, relatedAccID := range relatedAccountPRNs(resource) {
+		accID, err := accountResourceID(ctx, p, relatedAccID, u.useExternalID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, grant.NewGrant(resource, PrimaryAccount, accID))
+	}
+
+	if u.kycVerified && identityVerified(resource) {
+		kycID, err := rs.NewResourceID(verificationResourceType, p.resourceID(kycResourceID))
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to create KYC resource ID: %w", err)
+		}
+
+		rv = append(rv, grant.NewGrant(&v2.Resource{Id: kycID}, KYCVerified, resource.Id))
+	}
+
+	return rv, "", nil, nil
 }
 
-// Grants always returns an empty slice for users since they don't have any entitlements.
-func (u *userBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+func (u *userBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != userResourceType.Id {
+		l.Warn(
+			"galileo-ft-connector: only users can be granted as secondary accounts",
+			zap.String("principal_id", principal.Id.String()),
+			zap.String("principal_type", principal.Id.ResourceType),
+		)
+
+		return nil, fmt.Errorf("galileo-ft-connector: only users can be granted as secondary accounts")
+	}
+
+	p, primaryAccID, accID, err := u.parseGrant(principal, entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	// nothing is sent to Galileo in a dry run, so both accounts are checked instead
+	if p.client.DryRun() {
+		err = checkRelatedAccounts(ctx, p, primaryAccID, accID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = p.client.AddRelatedAccount(ctx, primaryAccID, accID)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to add secondary account: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (u *userBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	principal := grant.Principal
+	entitlement := grant.Entitlement
+
+	if principal.Id.ResourceType != userResourceType.Id {
+		l.Warn(
+			"galileo-ft-connector: only users can be removed as secondary accounts",
+			zap.String("principal_id", principal.Id.String()),
+			zap.String("principal_type", principal.Id.ResourceType),
+		)
+
+		return nil, fmt.Errorf("galileo-ft-connector: only users can be removed as secondary accounts")
+	}
+
+	p, primaryAccID, accID, err := u.parseGrant(principal, entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	// nothing is sent to Galileo in a dry run, so both accounts are checked instead
+	if p.client.DryRun() {
+		err = checkRelatedAccounts(ctx, p, primaryAccID, accID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = p.client.RemoveRelatedAccount(ctx, primaryAccID, accID)
+	if err != nil {
+		return nil, fmt.Errorf("galileo-ft-connector: failed to remove secondary account: %w", err)
+	}
+
+	return nil, nil
+}
+
+// checkRelatedAccounts fails with NotFound if the primary or the secondary account is unknown to Galileo or has no customer.
+func checkRelatedAccounts(ctx context.Context, p *provider, primaryAccID, accID string) error {
+	err := checkActionAccount(ctx, p, primaryAccID)
+	if err != nil {
+		return err
+	}
+
+	return checkActionAccount(ctx, p, accID)
+}
+
+// parseGrant returns the provider, the primary account PRN of the entitlement and the account PRN of the principal.
+// Accounts can only be related to accounts of the same provider.
+func (u *userBuilder) parseGrant(principal *v2.Resource, entitlement *v2.Entitlement) (*provider, string, string, error) {
+	p, accID, err := u.providers.parseAccount(principal)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("galileo-ft-connector: failed to parse principal: %w", err)
+	}
+
+	primaryProvider, primaryAccID, err := u.providers.parseAccount(entitlement.Resource)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("galileo-ft-connector: failed to parse primary account: %w", err)
+	}
+
+	if p != primaryProvider {
+		return nil, "", "", fmt.Errorf("galileo-ft-connector: accounts %s and %s belong to different providers", primaryAccID, accID)
+	}
+
+	return p, primaryAccID, accID, nil
 }
 
-func newUserBuilder(client *galileo.Client) *userBuilder {
+func newUserBuilder(providers *providerSet, state *syncState, progress *syncProgress, scope *scopeFilter, opts *Options) *userBuilder {
 	return &userBuilder{
-		client:       client,
-		resourceType: userResourceType,
+		providers:          providers,
+		resourceType:       userResourceType,
+		progress:           progress,
+		useExternalID:      opts.UseExternalID,
+		maxAccountFailures: opts.MaxAccountFailures,
+		placeholders:       opts.PlaceholderUsers,
+		scope:              scope,
+		businesses:         opts.BusinessResources,
+		identityProfile:    opts.IdentityProfile,
+		kycVerified:        opts.KYCVerified,
+		usage:              newUsageCollector(opts.Usage, progress),
+		state:              state,
 	}
 }
@@ -0,0 +1,164 @@
+package connector
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/conductorone/baton-galileo-ft/pkg/galileo"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// resourceIDs returns the IDs of the resources, sorted.
+func resourceIDs(resources []*v2.Resource) []string {
+	rv := make([]string, 0, len(resources))
+	for _, r := range resources {
+		rv = append(rv, r.Id.Resource)
+	}
+	sort.Strings(rv)
+
+	return rv
+}
+
+// conflictedAccounts returns the accounts reported as conflicts in the annotations, sorted.
+func conflictedAccounts(t *testing.T, annos annotations.Annotations) []string {
+	t.Helper()
+
+	var rv []string
+	for _, a := range annos {
+		st := &structpb.Struct{}
+		if !a.MessageIs(st) {
+			continue
+		}
+
+		if err := a.UnmarshalTo(st); err != nil {
+			t.Fatalf("failed to unmarshal annotation: %v", err)
+		}
+
+		if conflict := st.Fields["account_conflict"].GetStructValue(); conflict != nil {
+			rv = append(rv, conflict.Fields["account"].GetStringValue())
+		}
+	}
+	sort.Strings(rv)
+
+	return rv
+}
+
+func TestListRelatedAccountsAcrossGroups(t *testing.T) {
+	standIn := newGalileoStandIn()
+	standIn.addGroup("10", "", "Acme")
+	standIn.addGroup("20", "", "Globex")
+	standIn.addAccount("1001", "10", "jane@acme.example").related = []string{"3001", "2001"}
+	standIn.addAccount("2001", "20", "john@globex.example").related = []string{"3001"}
+	standIn.addAccount("3001", "", "jim@example.com")
+
+	ss := newSessionStandIn()
+	ctx := sessions.SetSyncIDInContext(context.Background(), "sync-1")
+
+	listUsers := func(g *Galileo, groupID string) ([]string, []string) {
+		t.Helper()
+
+		users := resourceSyncer(t, g, userResourceType)
+		parentID := &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: groupID}
+		resources, _, annos, err := users.List(ctx, parentID, &pagination.Token{})
+		if err != nil {
+			t.Fatalf("List() under group %s error = %v", groupID, err)
+		}
+
+		return resourceIDs(resources), conflictedAccounts(t, annos)
+	}
+
+	tests := []struct {
+		name string
+		// invocation starts another invocation of the connector within the sync before listing.
+		invocation bool
+		group      string
+		users      []string
+		conflicts  []string
+	}{
+		{name: "first owner", group: "10", users: []string{"1001", "3001"}, conflicts: []string{"2001"}},
+		{name: "other owner", group: "20", users: []string{"2001"}, conflicts: []string{"3001"}},
+		{name: "other owner in another invocation", invocation: true, group: "20", users: []string{"2001"}, conflicts: []string{"3001"}},
+		{name: "first owner listed again", invocation: true, group: "10", users: []string{"1001", "3001"}, conflicts: []string{"2001"}},
+	}
+
+	g := newStandInConnector(t, standIn, &Options{SessionStore: ss})
+	for _, tt := range tests {
+		if tt.invocation {
+			g = newStandInConnector(t, standIn, &Options{SessionStore: ss})
+		}
+
+		t.Run(tt.name, func(t *testing.T) {
+
+			users, conflicts := listUsers(g, tt.group)
+			if !reflect.DeepEqual(users, tt.users) {
+				t.Errorf("users = %v, want %v", users, tt.users)
+			}
+
+			if !reflect.DeepEqual(conflicts, tt.conflicts) {
+				t.Errorf("conflicts = %v, want %v", conflicts, tt.conflicts)
+			}
+		})
+	}
+}
+
+func TestRelatedAccountDryRun(t *testing.T) {
+	standIn := newGalileoStandIn()
+	standIn.addGroup("10", "", "Acme")
+	standIn.addAccount("1001", "10", "jane@acme.example").related = []string{"1002"}
+	standIn.addAccount("1002", "", "jim@acme.example")
+	standIn.addAccount("1003", "", "joe@acme.example")
+
+	account := func(prn string) *v2.Resource {
+		return &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: prn}}
+	}
+
+	entitlement := func(prn string) *v2.Entitlement {
+		return &v2.Entitlement{Id: "user:" + prn + ":" + PrimaryAccount, Resource: account(prn), Slug: PrimaryAccount}
+	}
+
+	tests := []struct {
+		name    string
+		primary string
+		account string
+		revoke  bool
+		want    codes.Code
+	}{
+		{name: "grant", primary: "1001", account: "1003", want: codes.OK},
+		{name: "grant of an unknown account", primary: "1001", account: "9999", want: codes.NotFound},
+		{name: "grant to an unknown primary account", primary: "9999", account: "1003", want: codes.NotFound},
+		{name: "revoke", primary: "1001", account: "1002", revoke: true, want: codes.OK},
+		{name: "revoke of an unknown account", primary: "1001", account: "9999", revoke: true, want: codes.NotFound},
+		{name: "revoke from an unknown primary account", primary: "9999", account: "1002", revoke: true, want: codes.NotFound},
+	}
+
+	g := newStandInConnector(t, standIn, &Options{DryRun: true})
+	users := resourceProvisioner(t, g, userResourceType)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.revoke {
+				_, err = users.Revoke(context.Background(), grant.NewGrant(account(tt.primary), PrimaryAccount, account(tt.account).Id))
+			} else {
+				_, err = users.Grant(context.Background(), account(tt.account), entitlement(tt.primary))
+			}
+
+			if status.Code(err) != tt.want {
+				t.Errorf("error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+
+	if calls := standIn.callCount(galileo.AddRelatedAccountEndpoint) + standIn.callCount(galileo.RemoveRelatedAccountEndpoint); calls != 0 {
+		t.Errorf("%d related accounts changed in a dry run", calls)
+	}
+}
@@ -0,0 +1,72 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const (
+	kycResourceID = "kyc"
+	KYCVerified   = "kyc-verified"
+)
+
+// verificationBuilder syncs a KYC resource per provider, whose kyc-verified entitlement is granted to the users
+// whose customer passed the identity verification, so policies can rely on it. It cannot be provisioned.
+type verificationBuilder struct {
+	providers *providerSet
+}
+
+func (v *verificationBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return verificationResourceType
+}
+
+func (v *verificationBuilder) List(_ context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID != nil {
+		return nil, "", nil, nil
+	}
+
+	rv := make([]*v2.Resource, 0, len(v.providers.list))
+	for _, p := range v.providers.list {
+		name := "KYC"
+		if p.label != "" {
+			name = fmt.Sprintf("KYC (%s)", p.label)
+		}
+
+		resource, err := rs.NewResource(name, verificationResourceType, p.resourceID(kycResourceID))
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("galileo-ft-connector: failed to create KYC resource: %w", err)
+		}
+
+		rv = append(rv, resource)
+	}
+
+	return rv, "", nil, nil
+}
+
+func (v *verificationBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	options := []ent.EntitlementOption{
+		ent.WithGrantableTo(userResourceType),
+		ent.WithDisplayName(fmt.Sprintf("%s verified", resource.DisplayName)),
+		ent.WithDescription("Customer passed the CIP and KYC verifications and did not match the OFAC list"),
+	}
+
+	return []*v2.Entitlement{ent.NewAssignmentEntitlement(resource, KYCVerified, options...)}, "", nil, nil
+}
+
+// Grants returns no grant, the kyc-verified entitlement is granted by the users whose customer passed the verification,
+// as recorded in their profile when they were listed.
+func (v *verificationBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newVerificationBuilder(providers *providerSet) *verificationBuilder {
+	return &verificationBuilder{
+		providers: providers,
+	}
+}
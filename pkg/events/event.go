@@ -0,0 +1,69 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Types of the events converted into Baton change events, other events are queued but ignored.
+// Galileo events are expected with these types unless the webhook maps their Galileo types to them.
+const (
+	TypeAccountStatusChange = "account_status_change"
+	TypeCardActivation      = "card_activation"
+	TypeGroupChange         = "group_change"
+	TypeAccountGroupChange  = "account_group_change"
+)
+
+var supportedTypes = map[string]bool{
+	TypeAccountStatusChange: true,
+	TypeCardActivation:      true,
+	TypeGroupChange:         true,
+	TypeAccountGroupChange:  true,
+}
+
+// Event is a Galileo event pushed to the webhook, as persisted in the queue.
+type Event struct {
+	ID         string `json:"event_id"`
+	Type       string `json:"event_type"`
+	ProviderID string `json:"provider_id,omitempty"`
+	// AccountNo is the PRN of the account the event is about, if any.
+	AccountNo string `json:"prn,omitempty"`
+	// GroupID is the group the event is about, or the group of the account.
+	GroupID   string    `json:"group_id,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// ReceivedAt is set by the webhook, events are fed in the order they are received.
+	ReceivedAt time.Time `json:"received_at"`
+	// Payload is the event as sent by Galileo.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// ParseEvent returns the event of a webhook request body.
+func ParseEvent(body []byte) (*Event, error) {
+	var e Event
+	err := json.Unmarshal(body, &e)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+
+	if e.ID == "" {
+		return nil, fmt.Errorf("invalid event: missing event_id")
+	}
+
+	if e.Type == "" {
+		return nil, fmt.Errorf("invalid event %s: missing event_type", e.ID)
+	}
+
+	e.Payload = body
+
+	return &e, nil
+}
+
+// OccurredAt returns when the event occurred, Galileo may omit the timestamp of the event.
+func (e *Event) OccurredAt() time.Time {
+	if e.Timestamp.IsZero() {
+		return e.ReceivedAt
+	}
+
+	return e.Timestamp
+}
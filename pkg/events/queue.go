@@ -0,0 +1,226 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	eventSuffix = ".json"
+	seenDir     = "seen"
+)
+
+// Entry is a queued event, with the cursor resuming the queue right after it.
+type Entry struct {
+	Cursor string
+	Event  *Event
+}
+
+// Queue is a durable queue of events, persisted as one JSON file per event in a local directory.
+// Files are named after the time the event was received so they are listed in order,
+// and a marker per event ID drops the events Galileo delivers more than once.
+type Queue struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewQueue(dir string) (*Queue, error) {
+	err := os.MkdirAll(filepath.Join(dir, seenDir), 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event queue directory: %w", err)
+	}
+
+	return &Queue{dir: dir}, nil
+}
+
+// eventKey returns a file name safe key of an event ID.
+func eventKey(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Push persists the event, it returns false if an event with the same ID was already queued.
+func (q *Queue) Push(e *Event) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := eventKey(e.ID)
+	seen, err := os.OpenFile(filepath.Join(q.dir, seenDir, key), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to mark event %s: %w", e.ID, err)
+	}
+	seen.Close()
+
+	if e.ReceivedAt.IsZero() {
+		e.ReceivedAt = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return false, errors.Join(fmt.Errorf("failed to marshal event %s: %w", e.ID, err), q.unmark(key))
+	}
+
+	name := fmt.Sprintf("%020d-%s%s", e.ReceivedAt.UnixNano(), key, eventSuffix)
+	err = writeFile(filepath.Join(q.dir, name), data)
+	if err != nil {
+		return false, errors.Join(fmt.Errorf("failed to write event %s: %w", e.ID, err), q.unmark(key))
+	}
+
+	return true, nil
+}
+
+// unmark removes the marker of an event that could not be queued, so a retry of Galileo is accepted.
+func (q *Queue) unmark(key string) error {
+	err := os.Remove(filepath.Join(q.dir, seenDir, key))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// writeFile writes the file atomically, readers never see a partially written event.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".event-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// names returns the file names of the queued events, in order.
+func (q *Queue) names() ([]string, error) {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list event queue: %w", err)
+	}
+
+	var rv []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasSuffix(name, eventSuffix) {
+			rv = append(rv, name)
+		}
+	}
+
+	sort.Strings(rv)
+
+	return rv, nil
+}
+
+// List returns up to limit events queued after the given cursor, the oldest first,
+// and whether more events follow them. An empty cursor lists the queue from its start.
+func (q *Queue) List(cursor string, limit int) ([]*Entry, bool, error) {
+	names, err := q.names()
+	if err != nil {
+		return nil, false, err
+	}
+
+	start := sort.SearchStrings(names, cursor)
+	if start < len(names) && names[start] == cursor {
+		start++
+	}
+
+	names = names[start:]
+	hasMore := len(names) > limit
+	if hasMore {
+		names = names[:limit]
+	}
+
+	rv := make([]*Entry, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(q.dir, name))
+		if err != nil {
+			// the event was pruned since the queue was listed
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, false, fmt.Errorf("failed to read event %s: %w", name, err)
+		}
+
+		var e Event
+		err = json.Unmarshal(data, &e)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse event %s: %w", name, err)
+		}
+
+		rv = append(rv, &Entry{Cursor: name, Event: &e})
+	}
+
+	return rv, hasMore, nil
+}
+
+// Prune removes the events received before the given time, with their markers, and returns how many were removed.
+func (q *Queue) Prune(before time.Time) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	names, err := q.names()
+	if err != nil {
+		return 0, err
+	}
+
+	bound := fmt.Sprintf("%020d", before.UnixNano())
+	removed := 0
+	for _, name := range names {
+		if name >= bound {
+			break
+		}
+
+		err = os.Remove(filepath.Join(q.dir, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("failed to remove event %s: %w", name, err)
+		}
+
+		removed++
+	}
+
+	// redeliveries are dropped for as long as events are kept
+	markers, err := os.ReadDir(filepath.Join(q.dir, seenDir))
+	if err != nil {
+		return removed, fmt.Errorf("failed to list event markers: %w", err)
+	}
+
+	for _, marker := range markers {
+		info, err := marker.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			continue
+		}
+
+		err = os.Remove(filepath.Join(q.dir, seenDir, marker.Name()))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("failed to remove event marker %s: %w", marker.Name(), err)
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestQueueList(t *testing.T) {
+	queue, err := NewQueue(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+
+	start := time.Now().UTC()
+	for i := 0; i < 5; i++ {
+		queued, err := queue.Push(&Event{
+			ID:         fmt.Sprintf("event-%d", i),
+			Type:       TypeAccountStatusChange,
+			ReceivedAt: start.Add(time.Duration(i) * time.Second),
+		})
+		if err != nil || !queued {
+			t.Fatalf("Push() = %v, %v", queued, err)
+		}
+	}
+
+	// redeliveries are dropped
+	queued, err := queue.Push(&Event{ID: "event-0", Type: TypeAccountStatusChange})
+	if err != nil || queued {
+		t.Fatalf("Push() of a duplicate = %v, %v", queued, err)
+	}
+
+	var ids []string
+	cursor := ""
+	for {
+		entries, hasMore, err := queue.List(cursor, 2)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+
+		for _, entry := range entries {
+			ids = append(ids, entry.Event.ID)
+			cursor = entry.Cursor
+		}
+
+		if !hasMore {
+			break
+		}
+	}
+
+	want := []string{"event-0", "event-1", "event-2", "event-3", "event-4"}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("listed %v, want %v", ids, want)
+	}
+
+	// the cursor of the last event resumes after it
+	entries, hasMore, err := queue.List(cursor, 2)
+	if err != nil || len(entries) != 0 || hasMore {
+		t.Errorf("List() after the last event = %d entries, %v, %v", len(entries), hasMore, err)
+	}
+}
+
+func TestQueuePrune(t *testing.T) {
+	queue, err := NewQueue(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+
+	now := time.Now().UTC()
+	for i, receivedAt := range []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Minute), now} {
+		_, err := queue.Push(&Event{ID: fmt.Sprintf("event-%d", i), Type: TypeGroupChange, ReceivedAt: receivedAt})
+		if err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	}
+
+	removed, err := queue.Prune(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	if removed != 1 {
+		t.Errorf("Prune() removed %d events, want 1", removed)
+	}
+
+	entries, _, err := queue.List("", 10)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	if len(entries) != 2 || entries[0].Event.ID != "event-1" {
+		t.Errorf("kept %d events, want event-1 and event-2", len(entries))
+	}
+}
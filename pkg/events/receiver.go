@@ -0,0 +1,152 @@
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSignatureHeader is the default header carrying the hex HMAC-SHA256 of the request body,
+	// keyed with the webhook secret. The header is set up with the event subscription, so it is configurable.
+	DefaultSignatureHeader = "X-Galileo-Signature"
+
+	signaturePrefix = "sha256="
+	maxBodySize     = 1 << 20
+)
+
+// VerifySignature reports whether the signature, optionally prefixed with "sha256=", is the signature of the body.
+func VerifySignature(secret, body []byte, signature string) bool {
+	got, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(signature), signaturePrefix))
+	if err != nil || len(got) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// Receiver is the HTTP handler of the Galileo event webhook, it queues every event with a valid signature.
+type Receiver struct {
+	secret          []byte
+	queue           *Queue
+	signatureHeader string
+	types           map[string]string
+}
+
+type ReceiverOption func(r *Receiver)
+
+// WithSignatureHeader sets the header carrying the signature of the events.
+func WithSignatureHeader(header string) ReceiverOption {
+	return func(r *Receiver) {
+		if header != "" {
+			r.signatureHeader = header
+		}
+	}
+}
+
+// WithEventTypes sets the Galileo event types of the event types of the connector, see ParseEventTypes.
+// Events are queued with the type of the connector, events of other types are queued as sent by Galileo.
+func WithEventTypes(types map[string]string) ReceiverOption {
+	return func(r *Receiver) {
+		r.types = types
+	}
+}
+
+func NewReceiver(secret string, queue *Queue, opts ...ReceiverOption) (*Receiver, error) {
+	if secret == "" {
+		return nil, errors.New("missing webhook secret")
+	}
+
+	r := &Receiver{
+		secret:          []byte(secret),
+		queue:           queue,
+		signatureHeader: DefaultSignatureHeader,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+// ParseEventTypes parses the Galileo event types of the event types of the connector,
+// given as type=galileo-type pairs, into a map from the Galileo type to the type of the connector.
+// Types that are not mapped are expected to be sent by Galileo as named by the connector.
+func ParseEventTypes(pairs []string) (map[string]string, error) {
+	types := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		eventType, galileoType, ok := strings.Cut(pair, "=")
+		eventType, galileoType = strings.TrimSpace(eventType), strings.TrimSpace(galileoType)
+		if !ok || galileoType == "" {
+			return nil, fmt.Errorf("invalid event type %q, expected type=galileo-type", pair)
+		}
+
+		if !supportedTypes[eventType] {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+
+		types[galileoType] = eventType
+	}
+
+	return types, nil
+}
+
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	l := ctxzap.Extract(req.Context())
+
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !VerifySignature(r.secret, body, req.Header.Get(r.signatureHeader)) {
+		l.Warn("galileo-ft-connector: rejected event with an invalid signature", zap.String("remote_addr", req.RemoteAddr))
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	e, err := ParseEvent(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if eventType, ok := r.types[e.Type]; ok {
+		e.Type = eventType
+	}
+
+	queued, err := r.queue.Push(e)
+	if err != nil {
+		// Galileo retries the events that are not acknowledged
+		l.Error("galileo-ft-connector: failed to queue event", zap.String("event_id", e.ID), zap.Error(err))
+		http.Error(w, "failed to queue event", http.StatusInternalServerError)
+		return
+	}
+
+	if queued {
+		l.Info("galileo-ft-connector: queued event", zap.String("event_id", e.ID), zap.String("event_type", e.Type))
+	} else {
+		l.Debug("galileo-ft-connector: dropped duplicate event", zap.String("event_id", e.ID))
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,168 @@
+package events
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := `{"event_id": "1", "event_type": "group_change"}`
+	signature := sign("secret", body)
+
+	tests := []struct {
+		name      string
+		signature string
+		want      bool
+	}{
+		{name: "valid", signature: signature, want: true},
+		{name: "prefixed", signature: "sha256=" + signature, want: true},
+		{name: "surrounding spaces", signature: " " + signature + " ", want: true},
+		{name: "other secret", signature: sign("other", body)},
+		{name: "other body", signature: sign("secret", body+" ")},
+		{name: "not hex", signature: "sha256=not-hex"},
+		{name: "empty", signature: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature([]byte("secret"), []byte(body), tt.signature); got != tt.want {
+				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEventTypes(t *testing.T) {
+	types, err := ParseEventTypes([]string{"group_change=GRP_UPD", " card_activation = CARD_ACT "})
+	if err != nil {
+		t.Fatalf("ParseEventTypes() error = %v", err)
+	}
+
+	if types["GRP_UPD"] != TypeGroupChange || types["CARD_ACT"] != TypeCardActivation {
+		t.Errorf("ParseEventTypes() = %v", types)
+	}
+
+	for _, pairs := range [][]string{{"group_change"}, {"group_change="}, {"unknown=GRP_UPD"}} {
+		if _, err := ParseEventTypes(pairs); err == nil {
+			t.Errorf("ParseEventTypes(%v) expected an error", pairs)
+		}
+	}
+}
+
+func TestReceiver(t *testing.T) {
+	queue, err := NewQueue(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+
+	receiver, err := NewReceiver(
+		"secret",
+		queue,
+		WithSignatureHeader("X-Signature"),
+		WithEventTypes(map[string]string{"GRP_UPD": TypeGroupChange}),
+	)
+	if err != nil {
+		t.Fatalf("NewReceiver() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		header string
+		secret string
+		want   int
+	}{
+		{
+			name:   "queued",
+			body:   `{"event_id": "1", "event_type": "GRP_UPD", "group_id": "42"}`,
+			header: "X-Signature",
+			want:   http.StatusAccepted,
+		},
+		{
+			name:   "duplicate",
+			body:   `{"event_id": "1", "event_type": "GRP_UPD", "group_id": "42"}`,
+			header: "X-Signature",
+			want:   http.StatusAccepted,
+		},
+		{
+			name:   "signature in another header",
+			body:   `{"event_id": "2", "event_type": "GRP_UPD"}`,
+			header: DefaultSignatureHeader,
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "invalid signature",
+			body:   `{"event_id": "3", "event_type": "GRP_UPD"}`,
+			header: "X-Signature",
+			secret: "other",
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "invalid event",
+			body:   `{"event_type": "GRP_UPD"}`,
+			header: "X-Signature",
+			want:   http.StatusBadRequest,
+		},
+		{
+			name:   "method",
+			method: http.MethodGet,
+			header: "X-Signature",
+			want:   http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, secret := tt.method, tt.secret
+			if method == "" {
+				method = http.MethodPost
+			}
+
+			if secret == "" {
+				secret = "secret"
+			}
+
+			req := httptest.NewRequest(method, "/", strings.NewReader(tt.body))
+			req.Header.Set(tt.header, sign(secret, tt.body))
+
+			rec := httptest.NewRecorder()
+			receiver.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	// only the first delivery of the valid event is queued, with the type of the connector
+	entries, hasMore, err := queue.List("", 10)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	if len(entries) != 1 || hasMore {
+		t.Fatalf("queued %d events (more: %v), want 1", len(entries), hasMore)
+	}
+
+	e := entries[0].Event
+	if e.ID != "1" || e.Type != TypeGroupChange || e.GroupID != "42" {
+		t.Errorf("queued event %+v", e)
+	}
+
+	if !strings.Contains(string(e.Payload), "GRP_UPD") {
+		t.Errorf("payload %s is not the event as sent by Galileo", e.Payload)
+	}
+}
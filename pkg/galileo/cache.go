@@ -0,0 +1,283 @@
+package galileo
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/session"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultCacheTTL        = 10 * time.Minute
+	DefaultCacheMaxEntries = 10000
+)
+
+// cachedEndpoints are the read-only endpoints whose responses are cached within a sync.
+var cachedEndpoints = map[string]bool{
+	RelatedAccountsEndpoint:  true,
+	AccountOverviewEndpoint:  true,
+	GroupHierarchyEndpoint:   true,
+	GroupInfoEndpoint:        true,
+	GroupsToAccountsEndpoint: true,
+}
+
+// mutatingEndpoints are the endpoints changing accounts or groups, a successful call invalidates the cache.
+var mutatingEndpoints = map[string]bool{
+	AddAccountToGroupEndpoint:      true,
+	RemoveAccountFromGroupEndpoint: true,
+	ModifyGroupEndpoint:            true,
+	AddRelatedAccountEndpoint:      true,
+	RemoveRelatedAccountEndpoint:   true,
+	ModifyStatusEndpoint:           true,
+	UpdateAccountEndpoint:          true,
+}
+
+// uncachedFormKeys are the form values that do not identify a request: credentials and the unique transaction ID.
+var uncachedFormKeys = map[string]bool{
+	"apiLogin":      true,
+	"apiTransKey":   true,
+	"transactionId": true,
+}
+
+// CacheStore stores the cached responses.
+// It has the signature of the SDK session store, which backs the cache within a sync, see NewSessionCacheStore.
+type CacheStore interface {
+	Get(ctx context.Context, key string, opt ...sessions.SessionStoreOption) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, opt ...sessions.SessionStoreOption) error
+	Clear(ctx context.Context, opt ...sessions.SessionStoreOption) error
+}
+
+// ResponseCache caches the responses of the read-only Galileo endpoints, so duplicate calls within a sync are not sent again.
+// It can be shared by the clients of several providers: responses are keyed by host, endpoint and form values.
+type ResponseCache struct {
+	store CacheStore
+	ttl   time.Duration
+}
+
+// NewResponseCache returns a cache keeping responses for the given TTL in the given store.
+func NewResponseCache(store CacheStore, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Reset drops all cached responses. It is called at the start of every sync.
+func (c *ResponseCache) Reset(ctx context.Context) error {
+	return c.store.Clear(ctx)
+}
+
+func (c *ResponseCache) get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok, err := c.store.Get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	// values are prefixed with their expiration time, the store itself does not need to support TTLs
+	expires, n := binary.Varint(value)
+	if n <= 0 || time.Now().UnixNano() > expires {
+		return nil, false, nil
+	}
+
+	return value[n:], true, nil
+}
+
+func (c *ResponseCache) set(ctx context.Context, key string, body []byte) error {
+	value := binary.AppendVarint(make([]byte, 0, binary.MaxVarintLen64+len(body)), time.Now().Add(c.ttl).UnixNano())
+
+	return c.store.Set(ctx, key, append(value, body...))
+}
+
+type noCacheKey struct{}
+
+// WithNoCache returns a context whose reads are sent to Galileo even if their response is cached,
+// e.g. for the reads a change is decided on. Their responses still refresh the cache.
+func WithNoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// noCache reports whether the reads of the context bypass the cache.
+func noCache(ctx context.Context) bool {
+	bypass, _ := ctx.Value(noCacheKey{}).(bool)
+	return bypass
+}
+
+// cacheKey returns the cache key of a request.
+func cacheKey(host, path string, form *url.Values) string {
+	values := url.Values{}
+	for k, v := range *form {
+		if !uncachedFormKeys[k] {
+			values[k] = v
+		}
+	}
+
+	// Encode sorts the values by key, so equal requests always have the same key.
+	sum := sha256.Sum256([]byte(host + path + "?" + values.Encode()))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// memoryCacheStore is an in-memory CacheStore evicting the least recently used entries above its size.
+type memoryCacheStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+type memoryCacheEntry struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCacheStore returns an in-memory CacheStore holding at most maxEntries responses.
+func NewMemoryCacheStore(maxEntries int) CacheStore {
+	return &memoryCacheStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+func (s *memoryCacheStore) Get(_ context.Context, key string, _ ...sessions.SessionStoreOption) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	s.lru.MoveToFront(e)
+
+	return e.Value.(*memoryCacheEntry).value, true, nil
+}
+
+func (s *memoryCacheStore) Set(_ context.Context, key string, value []byte, _ ...sessions.SessionStoreOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.Value.(*memoryCacheEntry).value = value
+		s.lru.MoveToFront(e)
+		return nil
+	}
+
+	s.entries[key] = s.lru.PushFront(&memoryCacheEntry{key: key, value: value})
+
+	for s.maxEntries > 0 && s.lru.Len() > s.maxEntries {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+
+	return nil
+}
+
+func (s *memoryCacheStore) Clear(_ context.Context, _ ...sessions.SessionStoreOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = make(map[string]*list.Element)
+	s.lru.Init()
+
+	return nil
+}
+
+// sessionCacheStore is a CacheStore backed by the SDK session store, which is kept by the SDK across the invocations
+// of the connector within a sync, unlike the memory of the connector.
+// Values are kept in the session of the sync of the context. Calls made outside of a sync, e.g. by provisioning,
+// and calls failing on the session store use the fallback store instead.
+type sessionCacheStore struct {
+	session sessions.SessionStore
+	// prefix namespaces the values of the store in the session of the sync.
+	prefix   string
+	fallback CacheStore
+	// disabled is set once the session store reports it is not enabled for the connector.
+	disabled atomic.Bool
+}
+
+// NewSessionCacheStore returns a CacheStore keeping values under the given prefix in the session store of the sync,
+// or in the fallback store when no session of a sync is available.
+func NewSessionCacheStore(session sessions.SessionStore, prefix string, fallback CacheStore) CacheStore {
+	return &sessionCacheStore{
+		session:  session,
+		prefix:   prefix,
+		fallback: fallback,
+	}
+}
+
+// options returns the options of the session of the sync of the context, false if the session store cannot be used.
+func (s *sessionCacheStore) options(ctx context.Context) ([]sessions.SessionStoreOption, bool) {
+	syncID := sessions.GetSyncID(ctx)
+	if syncID == "" || s.disabled.Load() {
+		return nil, false
+	}
+
+	return []sessions.SessionStoreOption{sessions.WithSyncID(syncID), sessions.WithPrefix(s.prefix)}, true
+}
+
+// failed logs a failure of the session store, the fallback store is used instead.
+func (s *sessionCacheStore) failed(ctx context.Context, op string, err error) {
+	if errors.Is(err, session.ErrSessionStoreDisabled) {
+		s.disabled.Store(true)
+	}
+
+	ctxzap.Extract(ctx).Warn(
+		"galileo-ft-connector: session store failed, falling back to the memory cache",
+		zap.String("operation", op),
+		zap.Error(err),
+	)
+}
+
+func (s *sessionCacheStore) Get(ctx context.Context, key string, _ ...sessions.SessionStoreOption) ([]byte, bool, error) {
+	if opts, ok := s.options(ctx); ok {
+		value, found, err := s.session.Get(ctx, key, opts...)
+		if err == nil {
+			return value, found, nil
+		}
+
+		s.failed(ctx, "get", err)
+	}
+
+	return s.fallback.Get(ctx, key)
+}
+
+func (s *sessionCacheStore) Set(ctx context.Context, key string, value []byte, _ ...sessions.SessionStoreOption) error {
+	if opts, ok := s.options(ctx); ok {
+		err := s.session.Set(ctx, key, value, opts...)
+		if err == nil {
+			return nil
+		}
+
+		s.failed(ctx, "set", err)
+	}
+
+	return s.fallback.Set(ctx, key, value)
+}
+
+func (s *sessionCacheStore) Clear(ctx context.Context, _ ...sessions.SessionStoreOption) error {
+	if opts, ok := s.options(ctx); ok {
+		err := s.session.Clear(ctx, opts...)
+		if err != nil {
+			s.failed(ctx, "clear", err)
+		}
+	}
+
+	return s.fallback.Clear(ctx)
+}
@@ -0,0 +1,125 @@
+package galileo
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-sdk/pkg/session"
+	"github.com/conductorone/baton-sdk/pkg/types/sessions"
+)
+
+// sessionStandIn is a stand-in of the SDK session store, keeping the values of every sync in memory.
+type sessionStandIn struct {
+	sessions.SessionStore
+
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newSessionStandIn() *sessionStandIn {
+	return &sessionStandIn{values: make(map[string][]byte)}
+}
+
+func (s *sessionStandIn) key(ctx context.Context, key string, opts []sessions.SessionStoreOption) string {
+	bag := &sessions.SessionStoreBag{}
+	for _, opt := range opts {
+		_ = opt(ctx, bag)
+	}
+
+	return bag.SyncID + "/" + bag.Prefix + "/" + key
+}
+
+func (s *sessionStandIn) Get(ctx context.Context, key string, opt ...sessions.SessionStoreOption) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[s.key(ctx, key, opt)]
+	return value, ok, nil
+}
+
+func (s *sessionStandIn) Set(ctx context.Context, key string, value []byte, opt ...sessions.SessionStoreOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[s.key(ctx, key, opt)] = value
+	return nil
+}
+
+func (s *sessionStandIn) Clear(ctx context.Context, opt ...sessions.SessionStoreOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prefix := s.key(ctx, "", opt)
+	for key := range s.values {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.values, key)
+		}
+	}
+
+	return nil
+}
+
+func TestSessionCacheStore(t *testing.T) {
+	ss := newSessionStandIn()
+	syncCtx := sessions.SetSyncIDInContext(context.Background(), "sync-1")
+
+	store := NewSessionCacheStore(ss, "responses", NewMemoryCacheStore(DefaultCacheMaxEntries))
+	if err := store.Set(syncCtx, "response", []byte("synced")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	if err := store.Set(context.Background(), "response", []byte("provisioned")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// another invocation of the connector within the sync finds the responses of the sync, not the others
+	resumed := NewSessionCacheStore(ss, "responses", NewMemoryCacheStore(DefaultCacheMaxEntries))
+	value, ok, err := resumed.Get(syncCtx, "response")
+	if err != nil || !ok || string(value) != "synced" {
+		t.Errorf("Get() within the sync = %q, %v, %v", value, ok, err)
+	}
+
+	_, ok, err = resumed.Get(sessions.SetSyncIDInContext(context.Background(), "sync-2"), "response")
+	if err != nil || ok {
+		t.Errorf("Get() within another sync = %v, %v, want no response", ok, err)
+	}
+
+	_, ok, err = resumed.Get(context.Background(), "response")
+	if err != nil || ok {
+		t.Errorf("Get() outside of a sync in another invocation = %v, %v, want no response", ok, err)
+	}
+
+	value, ok, err = store.Get(context.Background(), "response")
+	if err != nil || !ok || string(value) != "provisioned" {
+		t.Errorf("Get() outside of a sync = %q, %v, %v", value, ok, err)
+	}
+
+	if err := resumed.Clear(syncCtx); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+
+	_, ok, _ = store.Get(syncCtx, "response")
+	if ok {
+		t.Error("response of the sync still cached after Clear()")
+	}
+}
+
+func TestSessionCacheStoreDisabled(t *testing.T) {
+	syncCtx := sessions.SetSyncIDInContext(context.Background(), "sync-1")
+
+	store := NewSessionCacheStore(&session.NoOpSessionStore{}, "responses", NewMemoryCacheStore(DefaultCacheMaxEntries))
+	if err := store.Set(syncCtx, "response", []byte("synced")); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	value, ok, err := store.Get(syncCtx, "response")
+	if err != nil || !ok || string(value) != "synced" {
+		t.Errorf("Get() = %q, %v, %v, want the response kept in memory", value, ok, err)
+	}
+
+	if !store.(*sessionCacheStore).disabled.Load() {
+		t.Error("disabled session store is still used")
+	}
+}
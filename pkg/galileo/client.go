@@ -3,16 +3,21 @@ package galileo
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	BaseHost = "api-sandbox.cv.gpsrv.com"
-
 	RelatedAccountsEndpoint        = "/intserv/4.0/getRelatedAccounts"
 	AccountOverviewEndpoint        = "/intserv/4.0/getAccountOverview"
 	RootGroupsEndpoint             = "/intserv/4.0/getRootGroups"
@@ -21,47 +26,124 @@ const (
 	GroupsToAccountsEndpoint       = "/intserv/4.0/getAccountGroupRelationships"
 	AddAccountToGroupEndpoint      = "/intserv/4.0/setAccountGroupRelationships"
 	RemoveAccountFromGroupEndpoint = "/intserv/4.0/removeAccountGroupRelationship"
+	ModifyGroupEndpoint            = "/intserv/4.0/modifyGroup"
+	AddRelatedAccountEndpoint      = "/intserv/4.0/addRelatedAccount"
+	RemoveRelatedAccountEndpoint   = "/intserv/4.0/removeRelatedAccount"
+	ModifyStatusEndpoint           = "/intserv/4.0/modifyStatus"
+	TransHistoryEndpoint           = "/intserv/4.0/getTransHistory"
+	AuthHistoryEndpoint            = "/intserv/4.0/getAuthHistory"
+	UpdateAccountEndpoint          = "/intserv/4.0/updateAccount"
 
 	PingEndpoint = "/intserv/4.0/ping"
+
+	// GroupsInfoMaxIDs is the maximum number of group IDs sent in a single getGroupsInfo request.
+	GroupsInfoMaxIDs = 100
+
+	groupsInfoConcurrency = 4
 )
 
 type Config struct {
-	Hostname    string `mapstructure:"hostname"`
-	BaseURL     string `mapstructure:"base-url"`
-	APILogin    string `mapstructure:"api-login"`
-	APITransKey string `mapstructure:"api-trans-key"`
-	ProviderID  string `mapstructure:"provider-id"`
+	// Label identifies the provider when several Galileo programs are synced together.
+	Label       string `mapstructure:"label" json:"label"`
+	Environment string `mapstructure:"environment" json:"environment"`
+	Hostname    string `mapstructure:"hostname" json:"hostname"`
+	BaseURL     string `mapstructure:"base-url" json:"base-url"`
+	APILogin    string `mapstructure:"api-login" json:"api-login"`
+	APITransKey string `mapstructure:"api-trans-key" json:"api-trans-key"`
+	ProviderID  string `mapstructure:"provider-id" json:"provider-id"`
 }
 
 type Client struct {
-	httpClient *uhttp.BaseHttpClient
-	config     *Config
-	baseUrl    *url.URL
+	httpClient  *uhttp.BaseHttpClient
+	config      *Config
+	environment Environment
+	baseUrl     *url.URL
+	cache       *ResponseCache
+	telemetry   *telemetry
+	observer    CallObserver
+	dryRun      bool
+	identity    IdentityFields
 }
 
-func NewClient(httpClient *http.Client, config *Config) (*Client, error) {
-	b := &url.URL{
-		Scheme: "https",
-		Host:   BaseHost,
+// CallObserver is notified of every API call of the client, cached is set for calls answered from the response cache.
+type CallObserver func(ctx context.Context, endpoint string, cached bool)
+
+type ClientOption func(*Client)
+
+// WithCache caches the responses of the read-only endpoints in the given cache.
+func WithCache(cache *ResponseCache) ClientOption {
+	return func(c *Client) {
+		c.cache = cache
 	}
+}
 
-	// BaseURL takes precedence over Hostname if both are provided
-	if config.BaseURL != "" {
-		parsedURL, err := url.Parse(config.BaseURL)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse base URL: %w", err)
+// WithIdentityFields reads the identity verification of customers from the given profile fields,
+// the default name is kept for every empty name.
+func WithIdentityFields(names IdentityFields) ClientOption {
+	return func(c *Client) {
+		if names.CIPStatus != "" {
+			c.identity.CIPStatus = names.CIPStatus
+		}
+
+		if names.KYCResult != "" {
+			c.identity.KYCResult = names.KYCResult
 		}
-		b = parsedURL
-	} else if config.Hostname != "" {
-		// Deprecated: use BaseURL instead
-		b.Host = config.Hostname
+
+		if names.OFACMatch != "" {
+			c.identity.OFACMatch = names.OFACMatch
+		}
+	}
+}
+
+// WithCallObserver notifies the given observer of every API call.
+func WithCallObserver(observer CallObserver) ClientOption {
+	return func(c *Client) {
+		c.observer = observer
+	}
+}
+
+// WithDryRun logs the requests to the mutating endpoints instead of sending them, the calls succeed without a response.
+func WithDryRun() ClientOption {
+	return func(c *Client) {
+		c.dryRun = true
+	}
+}
+
+func NewClient(httpClient *http.Client, config *Config, opts ...ClientOption) (*Client, error) {
+	env, b, err := resolveEnvironment(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		httpClient:  uhttp.NewBaseHttpClient(httpClient),
+		config:      config,
+		environment: env,
+		baseUrl:     b,
+		telemetry:   newTelemetry(),
+		identity:    DefaultIdentityFields,
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
 
-	return &Client{
-		httpClient: uhttp.NewBaseHttpClient(httpClient),
-		config:     config,
-		baseUrl:    b,
-	}, nil
+	return c, nil
+}
+
+// Environment returns the Galileo environment the client sends requests to.
+func (c *Client) Environment() Environment {
+	return c.environment
+}
+
+// DryRun reports whether the requests to the mutating endpoints are only logged.
+func (c *Client) DryRun() bool {
+	return c.dryRun
+}
+
+// ProviderID returns the Galileo provider ID of the client.
+func (c *Client) ProviderID() string {
+	return c.config.ProviderID
 }
 
 func (c *Client) Ping(ctx context.Context) error {
@@ -98,12 +180,59 @@ func (c *Client) ListRelatedAccounts(ctx context.Context, accountID string) ([]A
 	return accounts.Data.Children, nil
 }
 
+// https://docs.galileo-ft.com/pro/reference/post_addrelatedaccount
+func (c *Client) AddRelatedAccount(ctx context.Context, primaryAccountID, accountID string) error {
+	data := &FormData{
+		APILogin:         c.config.APILogin,
+		APITransKey:      c.config.APITransKey,
+		ProviderID:       c.config.ProviderID,
+		AccountNo:        accountID,
+		PrimaryAccountNo: primaryAccountID,
+	}
+
+	err := c.post(ctx, AddRelatedAccountEndpoint, prepareForm(data), nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// https://docs.galileo-ft.com/pro/reference/post_removerelatedaccount
+func (c *Client) RemoveRelatedAccount(ctx context.Context, primaryAccountID, accountID string) error {
+	data := &FormData{
+		APILogin:         c.config.APILogin,
+		APITransKey:      c.config.APITransKey,
+		ProviderID:       c.config.ProviderID,
+		AccountNo:        accountID,
+		PrimaryAccountNo: primaryAccountID,
+	}
+
+	err := c.post(ctx, RemoveRelatedAccountEndpoint, prepareForm(data), nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 type AccountOverviewResponse struct {
-	Profile *Customer `json:"profile"`
+	Profile   *Customer `json:"profile"`
+	ProductID string    `json:"product_id"`
 }
 
-// https://docs.galileo-ft.com/pro/reference/post_getaccountoverview
+// GetCustomer returns the customer of the account, from the profile of its overview.
 func (c *Client) GetCustomer(ctx context.Context, accountID string) (*Customer, error) {
+	overview, err := c.GetAccountOverview(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	return overview.Profile, nil
+}
+
+// https://docs.galileo-ft.com/pro/reference/post_getaccountoverview
+func (c *Client) GetAccountOverview(ctx context.Context, accountID string) (*AccountOverviewResponse, error) {
 	var res BaseResponse[AccountOverviewResponse]
 
 	data := &FormData{
@@ -118,7 +247,11 @@ func (c *Client) GetCustomer(ctx context.Context, accountID string) (*Customer,
 		return nil, err
 	}
 
-	return res.Data.Profile, nil
+	if res.Data.Profile != nil {
+		res.Data.Profile.setIdentity(c.identity)
+	}
+
+	return &res.Data, nil
 }
 
 // https://docs.galileo-ft.com/pro/reference/post_getrootgroups
@@ -158,6 +291,20 @@ func mapGroupIDs(groups []GroupHierarchy) []string {
 
 // https://docs.galileo-ft.com/pro/reference/post_getgrouphierarchy
 func (c *Client) ListChildrenGroups(ctx context.Context, parentGroupID string) ([]string, error) {
+	hierarchy, err := c.GetGroupHierarchy(ctx, parentGroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The maximum number of levels below a root group is five, making six levels total.
+	ids := mapGroupIDs(hierarchy)
+
+	return ids, nil
+}
+
+// GetGroupHierarchy returns the tree of the groups below the given group.
+// https://docs.galileo-ft.com/pro/reference/post_getgrouphierarchy
+func (c *Client) GetGroupHierarchy(ctx context.Context, parentGroupID string) ([]GroupHierarchy, error) {
 	var res BaseResponse[[]GroupHierarchy]
 
 	data := &FormData{
@@ -172,14 +319,108 @@ func (c *Client) ListChildrenGroups(ctx context.Context, parentGroupID string) (
 		return nil, err
 	}
 
-	// The maximum number of levels below a root group is five, making six levels total.
-	ids := mapGroupIDs(res.Data)
+	return res.Data, nil
+}
 
-	return ids, nil
+// GetGroupsInfo returns the information of the given groups.
+// IDs are sent in chunks of GroupsInfoMaxIDs, fetched in parallel. When Galileo rejects a chunk, it is split until the rejected IDs
+// are isolated, so the groups that could be fetched are returned along with the error of every group that was rejected.
+// Any other failure, e.g. a server error, a timeout or rate limiting, fails the whole request so that it is retried
+// rather than dropping the groups of the chunk. An error is also returned if no group could be fetched at all.
+func (c *Client) GetGroupsInfo(ctx context.Context, groupIDs []string) ([]Group, map[string]error, error) {
+	chunks := chunkIDs(groupIDs, GroupsInfoMaxIDs)
+	results := make([]groupsInfoResult, len(chunks))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, groupsInfoConcurrency)
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = c.getGroupsInfoChunk(ctx, chunk)
+		}()
+	}
+	wg.Wait()
+
+	var groups []Group
+	failures := make(map[string]error)
+	for _, r := range results {
+		if r.err != nil {
+			return nil, nil, r.err
+		}
+
+		groups = append(groups, r.groups...)
+		for id, err := range r.failures {
+			failures[id] = err
+		}
+	}
+
+	if len(groups) == 0 && len(failures) > 0 {
+		for _, id := range groupIDs {
+			if err, ok := failures[id]; ok {
+				return nil, failures, fmt.Errorf("failed to get info of %d groups, first error for group %s: %w", len(failures), id, err)
+			}
+		}
+	}
+
+	return groups, failures, nil
+}
+
+type groupsInfoResult struct {
+	groups   []Group
+	failures map[string]error
+	// err is a failure of the request that is not a rejection of its IDs.
+	err error
+}
+
+// getGroupsInfoChunk fetches a chunk of groups, bisecting it when Galileo rejects it to isolate the rejected IDs.
+func (c *Client) getGroupsInfoChunk(ctx context.Context, groupIDs []string) groupsInfoResult {
+	groups, err := c.getGroupsInfo(ctx, groupIDs)
+	if err == nil {
+		return groupsInfoResult{
+			groups:   groups,
+			failures: missingGroups(groupIDs, groups),
+		}
+	}
+
+	if !rejected(err) || ctx.Err() != nil {
+		return groupsInfoResult{err: err}
+	}
+
+	if len(groupIDs) == 1 {
+		return groupsInfoResult{failures: map[string]error{groupIDs[0]: err}}
+	}
+
+	half := len(groupIDs) / 2
+	left := c.getGroupsInfoChunk(ctx, groupIDs[:half])
+	if left.err != nil {
+		return left
+	}
+
+	right := c.getGroupsInfoChunk(ctx, groupIDs[half:])
+	if right.err != nil {
+		return right
+	}
+
+	for id, err := range right.failures {
+		if left.failures == nil {
+			left.failures = make(map[string]error)
+		}
+		left.failures[id] = err
+	}
+
+	return groupsInfoResult{
+		groups:   append(left.groups, right.groups...),
+		failures: left.failures,
+	}
 }
 
 // https://docs.galileo-ft.com/pro/reference/post_getgroupsinfo
-func (c *Client) GetGroupsInfo(ctx context.Context, groupIDs []string) ([]Group, error) {
+func (c *Client) getGroupsInfo(ctx context.Context, groupIDs []string) ([]Group, error) {
 	var res BaseResponse[[]Group]
 
 	data := &FormData{
@@ -197,6 +438,149 @@ func (c *Client) GetGroupsInfo(ctx context.Context, groupIDs []string) ([]Group,
 	return res.Data, nil
 }
 
+// missingGroups returns an error for every requested group that is not in the response.
+func missingGroups(groupIDs []string, groups []Group) map[string]error {
+	found := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		found[g.ID] = true
+	}
+
+	var failures map[string]error
+	for _, id := range groupIDs {
+		if found[id] {
+			continue
+		}
+
+		if failures == nil {
+			failures = make(map[string]error)
+		}
+		failures[id] = status.Errorf(codes.NotFound, "group %s not found", id)
+	}
+
+	return failures
+}
+
+func chunkIDs(ids []string, size int) [][]string {
+	var chunks [][]string
+	for size < len(ids) {
+		chunks = append(chunks, ids[:size:size])
+		ids = ids[size:]
+	}
+
+	if len(ids) > 0 {
+		chunks = append(chunks, ids)
+	}
+
+	return chunks
+}
+
+// https://docs.galileo-ft.com/pro/reference/post_modifygroup
+func (c *Client) SetGroupPrimaryContact(ctx context.Context, groupID, contactName, contactEmail string) error {
+	data := &FormData{
+		APILogin:    c.config.APILogin,
+		APITransKey: c.config.APITransKey,
+		ProviderID:  c.config.ProviderID,
+		GroupID:     groupID,
+	}
+
+	// contact is set even when empty, which clears the primary contact of the group
+	form := prepareForm(data)
+	form.Set("primaryContactName", contactName)
+	form.Set("primaryContactEmail", contactEmail)
+
+	err := c.post(ctx, ModifyGroupEndpoint, form, nil)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ModifyCardStatus applies the card operation to the card of the account, and returns the card resulting from it.
+// https://docs.galileo-ft.com/pro/reference/post_modifystatus
+func (c *Client) ModifyCardStatus(ctx context.Context, accountID string, op CardOperation) (*CardStatus, error) {
+	var res BaseResponse[CardStatus]
+
+	data := &FormData{
+		APILogin:    c.config.APILogin,
+		APITransKey: c.config.APITransKey,
+		ProviderID:  c.config.ProviderID,
+		AccountNo:   accountID,
+	}
+
+	form := prepareForm(data)
+	form.Set("type", string(op))
+
+	err := c.post(ctx, ModifyStatusEndpoint, form, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Data, nil
+}
+
+// UpdateAccount changes the customer profile fields of the account set in the update.
+// https://docs.galileo-ft.com/pro/reference/post_updateaccount
+func (c *Client) UpdateAccount(ctx context.Context, accountID string, update *ProfileUpdate) error {
+	data := &FormData{
+		APILogin:    c.config.APILogin,
+		APITransKey: c.config.APITransKey,
+		ProviderID:  c.config.ProviderID,
+		AccountNo:   accountID,
+	}
+
+	form := prepareForm(data)
+	for key, value := range update.formValues() {
+		if value != "" {
+			form.Set(key, value)
+		}
+	}
+
+	return c.post(ctx, UpdateAccountEndpoint, form, nil)
+}
+
+// ListTransactions returns a page of the transactions posted to the account between the start and end dates, and the number of pages.
+// https://docs.galileo-ft.com/pro/reference/post_gettranshistory
+func (c *Client) ListTransactions(ctx context.Context, accountID string, start, end time.Time, pgVars *PaginationVars) ([]Transaction, uint, error) {
+	var res ListResponse[Transaction]
+
+	err := c.postHistory(ctx, TransHistoryEndpoint, accountID, start, end, pgVars, &res)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return res.Data, res.NumOfPages, nil
+}
+
+// ListAuthorizations returns a page of the authorizations of the account between the start and end dates, and the number of pages.
+// https://docs.galileo-ft.com/pro/reference/post_getauthhistory
+func (c *Client) ListAuthorizations(ctx context.Context, accountID string, start, end time.Time, pgVars *PaginationVars) ([]Authorization, uint, error) {
+	var res ListResponse[Authorization]
+
+	err := c.postHistory(ctx, AuthHistoryEndpoint, accountID, start, end, pgVars, &res)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return res.Data, res.NumOfPages, nil
+}
+
+func (c *Client) postHistory(ctx context.Context, path, accountID string, start, end time.Time, pgVars *PaginationVars, response interface{}) error {
+	data := &FormData{
+		APILogin:    c.config.APILogin,
+		APITransKey: c.config.APITransKey,
+		ProviderID:  c.config.ProviderID,
+		AccountNo:   accountID,
+	}
+
+	form := prepareForm(data)
+	form.Set("startDate", start.Format(DateFormat))
+	form.Set("endDate", end.Format(DateFormat))
+	pgVars.PrepareVars(form)
+
+	return c.post(ctx, path, form, response)
+}
+
 // https://docs.galileo-ft.com/pro/reference/post_getaccountgrouprelationships
 func (c *Client) ListGroupMembers(ctx context.Context, groupID string) (*GroupToAccounts, error) {
 	var res BaseResponse[[]GroupToAccounts]
@@ -217,17 +601,52 @@ func (c *Client) ListGroupMembers(ctx context.Context, groupID string) (*GroupTo
 		return nil, fmt.Errorf("unexpected number of group to accounts responses: %d", len(res.Data))
 	}
 
+	// a group without accounts may have no relationship at all
+	if len(res.Data) == 0 {
+		return &GroupToAccounts{GroupID: groupID}, nil
+	}
+
 	return &res.Data[0], nil
 }
 
+// GetAccountGroup returns the ID of the group the account belongs to, or an empty ID if it belongs to none.
+// https://docs.galileo-ft.com/pro/reference/post_getaccountgrouprelationships
+func (c *Client) GetAccountGroup(ctx context.Context, accountID string) (string, error) {
+	var res BaseResponse[[]GroupToAccounts]
+
+	data := &FormData{
+		APILogin:    c.config.APILogin,
+		APITransKey: c.config.APITransKey,
+		ProviderID:  c.config.ProviderID,
+		AccountNo:   accountID,
+	}
+
+	err := c.post(ctx, GroupsToAccountsEndpoint, prepareForm(data), &res)
+	if err != nil {
+		return "", err
+	}
+
+	// an account can belong to only one group at a time
+	if len(res.Data) > 1 {
+		return "", fmt.Errorf("unexpected number of account to group responses: %d", len(res.Data))
+	}
+
+	if len(res.Data) == 0 {
+		return "", nil
+	}
+
+	return res.Data[0].GroupID, nil
+}
+
+// AddAccountToGroup moves the account of the membership to its group.
 // https://docs.galileo-ft.com/pro/reference/post_setaccountgrouprelationships
-func (c *Client) AddAccountToGroup(ctx context.Context, groupID, accountID string) error {
+func (c *Client) AddAccountToGroup(ctx context.Context, membership Membership) error {
 	data := &FormData{
 		APILogin:    c.config.APILogin,
 		APITransKey: c.config.APITransKey,
 		ProviderID:  c.config.ProviderID,
-		GroupID:     groupID,
-		AccountIDs:  []string{accountID},
+		GroupID:     membership.GroupID,
+		AccountIDs:  []string{membership.AccountID},
 	}
 
 	err := c.post(ctx, AddAccountToGroupEndpoint, prepareForm(data), nil)
@@ -238,13 +657,15 @@ func (c *Client) AddAccountToGroup(ctx context.Context, groupID, accountID strin
 	return nil
 }
 
+// RemoveAccountFromGroup removes the account of the membership from its group.
+// Galileo removes the account from the group it belongs to, the caller checks it is the group of the membership.
 // https://docs.galileo-ft.com/pro/reference/post_removeaccountgrouprelationship
-func (c *Client) RemoveAccountFromGroup(ctx context.Context, groupID, accountID string) error {
+func (c *Client) RemoveAccountFromGroup(ctx context.Context, membership Membership) error {
 	data := &FormData{
 		APILogin:    c.config.APILogin,
 		APITransKey: c.config.APITransKey,
 		ProviderID:  c.config.ProviderID,
-		AccountIDs:  []string{accountID},
+		AccountIDs:  []string{membership.AccountID},
 	}
 
 	err := c.post(ctx, RemoveAccountFromGroupEndpoint, prepareForm(data), nil)
@@ -275,21 +696,112 @@ func (c *Client) createRequest(ctx context.Context, path string, form *url.Value
 }
 
 func (c *Client) post(ctx context.Context, path string, form *url.Values, response interface{}) error {
+	if c.dryRun && mutatingEndpoints[path] {
+		c.logDryRun(ctx, path, form)
+		return nil
+	}
+
+	key := cacheKey(c.baseUrl.Host, path, form)
+	ctx, call := c.telemetry.startCall(ctx, path, c.providerName(), key)
+
+	cached := c.cache != nil && response != nil && cachedEndpoints[path]
+	if cached && !noCache(ctx) {
+		body, ok, err := c.cache.get(ctx, key)
+		if err != nil {
+			ctxzap.Extract(ctx).Warn("galileo-ft-connector: failed to read response cache", zap.Error(err))
+		}
+
+		if ok {
+			call.cacheHit()
+			c.observe(ctx, path, true)
+			return json.Unmarshal(body, response)
+		}
+	}
+
+	c.observe(ctx, path, false)
+
 	req, err := c.createRequest(ctx, path, form)
 	if err != nil {
+		call.end(ctx, 0, err)
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	resp, err := c.httpClient.Do(req, uhttp.WithJSONResponse(response), WithErrorResponse())
+	var body []byte
+	options := []uhttp.DoOption{call.captureResponse(), WithErrorResponse()}
+	if response != nil {
+		options = append(options, uhttp.WithJSONResponse(response), withResponseBody(&body))
+	}
+
+	resp, err := c.httpClient.Do(req, options...)
+	call.end(ctx, len(form.Encode()), err)
 	if err != nil {
 		return fmt.Errorf("failed to do request: %w", err)
 	}
 
 	defer resp.Body.Close()
 
+	switch {
+	case cached:
+		err = c.cache.set(ctx, key, body)
+		if err != nil {
+			ctxzap.Extract(ctx).Warn("galileo-ft-connector: failed to write response cache", zap.Error(err))
+		}
+	case c.cache != nil && mutatingEndpoints[path]:
+		// cached responses may not reflect the change anymore
+		err = c.cache.store.Clear(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to invalidate response cache: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// logDryRun logs the request that would be sent to a mutating endpoint, without the credentials.
+func (c *Client) logDryRun(ctx context.Context, path string, form *url.Values) {
+	u := *c.baseUrl
+	u.Path = path
+
+	values := make(map[string][]string, len(*form))
+	for key, v := range *form {
+		if credentialFormKeys[key] {
+			v = []string{redactedValue}
+		}
+		values[key] = v
+	}
+
+	ctxzap.Extract(ctx).Info(
+		"galileo-ft-connector: dry run, request not sent",
+		zap.String("provider", c.providerName()),
+		zap.String("method", http.MethodPost),
+		zap.String("url", u.String()),
+		zap.Any("form", values),
+	)
+}
+
+func (c *Client) observe(ctx context.Context, path string, cached bool) {
+	if c.observer != nil {
+		c.observer(ctx, path, cached)
+	}
+}
+
+// providerName identifies the provider of the client in telemetry.
+func (c *Client) providerName() string {
+	if c.config.Label != "" {
+		return c.config.Label
+	}
+
+	return c.config.ProviderID
+}
+
+// withResponseBody keeps the raw body of the response, e.g. to cache it.
+func withResponseBody(body *[]byte) uhttp.DoOption {
+	return func(resp *uhttp.WrapperResponse) error {
+		*body = resp.Body
+		return nil
+	}
+}
+
 func checkContentType(contentType string) error {
 	if contentType != "application/json" {
 		return fmt.Errorf("unexpected content type %s", contentType)
@@ -298,14 +810,61 @@ func checkContentType(contentType string) error {
 	return nil
 }
 
+// StatusAccountNotFound is the Galileo status of the requests on an account that does not exist or is not available to the program.
+// More information about error status codes can be found here: https://docs.galileo-ft.com/pro/reference/api-reference-global-response-statuses
+const StatusAccountNotFound uint = 401
+
+// accountStatuses are the Galileo statuses of the requests rejected because of their account rather than the request itself.
+var accountStatuses = map[uint]bool{
+	StatusAccountNotFound: true,
+}
+
 // More information about error status codes can be found here: https://docs.galileo-ft.com/pro/reference/api-reference-global-response-statuses
 type ErrorResponse struct {
 	Code   uint   `json:"status_code"`
 	Status string `json:"status"`
 }
 
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Status, e.Code)
+}
+
+// AccountError reports whether Galileo rejected the request because of its account, e.g. an unknown or closed account.
+func (e *ErrorResponse) AccountError() bool {
+	return accountStatuses[e.Code]
+}
+
+// rejected reports whether Galileo processed and rejected the request itself, e.g. for an invalid parameter.
+// Failures to process it are not rejections: server errors, timeouts, rate limiting and authentication failures
+// may succeed when the request is sent again.
+func rejected(err error) bool {
+	var galileoErr *ErrorResponse
+	if !errors.As(err, &galileoErr) || galileoErr.Code == 0 {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.FailedPrecondition, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// WithErrorResponse returns the Galileo status of the failed requests as an ErrorResponse.
+// Galileo rejects some requests with a 200 response and a non-zero status code, they fail the same way.
 func WithErrorResponse() uhttp.DoOption {
 	return func(resp *uhttp.WrapperResponse) error {
+		if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+			// the body of a successful response is checked by the response decoder
+			var response ErrorResponse
+			if err := json.Unmarshal(resp.Body, &response); err != nil || response.Code == 0 {
+				return nil
+			}
+
+			return &response
+		}
+
 		if err := checkContentType(resp.Header.Get("Content-Type")); err != nil {
 			return fmt.Errorf("%w - %v", err, string(resp.Body))
 		}
@@ -315,6 +874,6 @@ func WithErrorResponse() uhttp.DoOption {
 			return fmt.Errorf("failed to unmarshal response: %w", err)
 		}
 
-		return fmt.Errorf("%s (%d)", response.Status, response.Code)
+		return &response
 	}
 }
@@ -0,0 +1,202 @@
+package galileo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newStandInClient returns a cached client of a Galileo stand-in answering every request with the handler.
+func newStandInClient(t *testing.T, handler http.HandlerFunc, opts ...ClientOption) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	opts = append(opts, WithCache(NewResponseCache(NewMemoryCacheStore(DefaultCacheMaxEntries), time.Minute)))
+	client, err := NewClient(
+		server.Client(),
+		&Config{
+			Label:       "test",
+			Environment: string(EnvironmentCustom),
+			BaseURL:     server.URL,
+			APILogin:    "login",
+			APITransKey: "key",
+			ProviderID:  "provider",
+		},
+		opts...,
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	return client
+}
+
+func TestListGroupMembers(t *testing.T) {
+	client := newStandInClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.FormValue("groupId") == "empty" {
+			_, _ = w.Write([]byte(`{"status_code": 0, "response_data": []}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"status_code": 0, "response_data": [{"group_id": "42", "pmt_ref_no": ["1", "2"]}]}`))
+	})
+
+	ctx := context.Background()
+
+	group, err := client.ListGroupMembers(ctx, "empty")
+	if err != nil {
+		t.Fatalf("ListGroupMembers() error = %v", err)
+	}
+
+	if group.GroupID != "empty" || len(group.AccountIDs) != 0 {
+		t.Errorf("ListGroupMembers() of a group without accounts = %+v", group)
+	}
+
+	group, err = client.ListGroupMembers(ctx, "42")
+	if err != nil {
+		t.Fatalf("ListGroupMembers() error = %v", err)
+	}
+
+	if group.GroupID != "42" || len(group.AccountIDs) != 2 {
+		t.Errorf("ListGroupMembers() = %+v", group)
+	}
+}
+
+func TestWithNoCache(t *testing.T) {
+	calls := 0
+	client := newStandInClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status_code": 0, "response_data": [{"group_id": "42", "pmt_ref_no": ["1"]}]}`))
+	})
+
+	ctx := context.Background()
+	for _, ctx := range []context.Context{ctx, ctx, WithNoCache(ctx), ctx} {
+		if _, err := client.GetAccountGroup(ctx, "1"); err != nil {
+			t.Fatalf("GetAccountGroup() error = %v", err)
+		}
+	}
+
+	// the read bypassing the cache is sent again, the others are answered from the cache
+	if calls != 2 {
+		t.Errorf("sent %d requests, want 2", calls)
+	}
+}
+
+func TestGetGroupsInfo(t *testing.T) {
+	ids := make([]string, 0, GroupsInfoMaxIDs+10)
+	for i := 0; i < cap(ids); i++ {
+		ids = append(ids, strconv.Itoa(i))
+	}
+
+	tests := []struct {
+		name string
+		// failing is the group whose requests fail, with the status and body of the failure.
+		failing string
+		status  int
+		body    string
+		// wantGroups is the number of groups returned, wantErr whether the whole request fails.
+		wantGroups int
+		wantErr    bool
+	}{
+		{
+			name:       "all fetched",
+			wantGroups: len(ids),
+		},
+		{
+			name:       "rejected group",
+			failing:    "42",
+			status:     http.StatusBadRequest,
+			body:       `{"status_code": 465, "status": "Invalid group ID"}`,
+			wantGroups: len(ids) - 1,
+		},
+		{
+			name:       "rejected group with a successful response",
+			failing:    "42",
+			status:     http.StatusOK,
+			body:       `{"status_code": 465, "status": "Invalid group ID"}`,
+			wantGroups: len(ids) - 1,
+		},
+		{
+			name:    "server error",
+			failing: "42",
+			status:  http.StatusServiceUnavailable,
+			body:    `{"status_code": 500, "status": "Service unavailable"}`,
+			wantErr: true,
+		},
+		{
+			name:    "rate limited",
+			failing: "42",
+			status:  http.StatusTooManyRequests,
+			body:    `{"status_code": 429, "status": "Too many requests"}`,
+			wantErr: true,
+		},
+		{
+			name:    "authentication failure",
+			failing: "42",
+			status:  http.StatusUnauthorized,
+			body:    `{"status_code": 2, "status": "Invalid credentials"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newStandInClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+
+				if err := r.ParseForm(); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				var groups []Group
+				for _, id := range r.PostForm["groupIds"] {
+					if id == tt.failing {
+						w.WriteHeader(tt.status)
+						_, _ = w.Write([]byte(tt.body))
+						return
+					}
+
+					groups = append(groups, Group{ID: id})
+				}
+
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"status_code": 0, "response_data": groups})
+			})
+
+			groups, failures, err := client.GetGroupsInfo(context.Background(), ids)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("GetGroupsInfo() returned %d groups and %d failures, expected an error", len(groups), len(failures))
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("GetGroupsInfo() error = %v", err)
+			}
+
+			if len(groups) != tt.wantGroups {
+				t.Errorf("GetGroupsInfo() returned %d groups, want %d", len(groups), tt.wantGroups)
+			}
+
+			if tt.failing == "" && len(failures) != 0 {
+				t.Errorf("GetGroupsInfo() failures = %v", failures)
+			}
+
+			if tt.failing != "" && (len(failures) != 1 || failures[tt.failing] == nil) {
+				t.Errorf("GetGroupsInfo() failures = %v, want only %s", failures, tt.failing)
+			}
+		})
+	}
+}
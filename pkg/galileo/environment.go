@@ -0,0 +1,73 @@
+package galileo
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Environment is a named Galileo API environment.
+type Environment string
+
+const (
+	EnvironmentSandbox    Environment = "sandbox"
+	EnvironmentProduction Environment = "production"
+	// EnvironmentCustom uses the base URL (or hostname) from the configuration, e.g. for program specific hosts.
+	EnvironmentCustom Environment = "custom"
+
+	SandboxHost    = "api-sandbox.cv.gpsrv.com"
+	ProductionHost = "api.cv.gpsrv.com"
+)
+
+// Environments lists the environments that can be selected in the configuration.
+var Environments = []string{
+	string(EnvironmentSandbox),
+	string(EnvironmentProduction),
+	string(EnvironmentCustom),
+}
+
+var environmentHosts = map[Environment]string{
+	EnvironmentSandbox:    SandboxHost,
+	EnvironmentProduction: ProductionHost,
+}
+
+// resolveEnvironment returns the environment and the API base URL of the configuration.
+// There is no default environment: the environment must be selected explicitly,
+// a base URL or hostname is only used with the custom environment selected.
+func resolveEnvironment(config *Config) (Environment, *url.URL, error) {
+	env := Environment(config.Environment)
+	custom := config.BaseURL != "" || config.Hostname != ""
+
+	switch env {
+	case "":
+		if custom {
+			return "", nil, fmt.Errorf("no environment specified, a base URL or hostname requires the %s environment", EnvironmentCustom)
+		}
+
+		return "", nil, fmt.Errorf("no environment specified, choose one of %v", Environments)
+	case EnvironmentSandbox, EnvironmentProduction:
+		if custom {
+			return "", nil, fmt.Errorf("base URL and hostname can only be set with the %s environment", EnvironmentCustom)
+		}
+
+		return env, &url.URL{Scheme: "https", Host: environmentHosts[env]}, nil
+	case EnvironmentCustom:
+		if !custom {
+			return "", nil, fmt.Errorf("the %s environment requires a base URL", EnvironmentCustom)
+		}
+	default:
+		return "", nil, fmt.Errorf("unknown environment %q, choose one of %v", env, Environments)
+	}
+
+	// BaseURL takes precedence over Hostname if both are provided
+	if config.BaseURL != "" {
+		parsedURL, err := url.Parse(config.BaseURL)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to parse base URL: %w", err)
+		}
+
+		return env, parsedURL, nil
+	}
+
+	// Deprecated: use BaseURL instead
+	return env, &url.URL{Scheme: "https", Host: config.Hostname}, nil
+}
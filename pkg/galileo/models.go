@@ -1,5 +1,17 @@
 package galileo
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DateFormat and TimestampFormat are the layouts of the dates and timestamps of the Galileo API.
+const (
+	DateFormat      = "2006-01-02"
+	TimestampFormat = "2006-01-02 15:04:05"
+)
+
 type BaseResponse[T any] struct {
 	Data T `json:"response_data"`
 }
@@ -23,6 +35,9 @@ type Account struct {
 }
 
 type Customer struct {
+	ExternalID string `json:"external_id"`
+	CustomerID string `json:"customer_id"`
+
 	FirstName  string `json:"first_name"`
 	MiddleName string `json:"middle_name"`
 	LastName   string `json:"last_name"`
@@ -36,6 +51,127 @@ type Customer struct {
 	CountryCode string `json:"country_code"`
 	HomePhone   string `json:"home_phone"`
 	MobilePhone string `json:"mobile_phone"`
+
+	// Identity verification of the customer: CIP status, KYC result and whether the customer matched the OFAC list.
+	// They are read from the profile fields named by the IdentityFields of the client.
+	CIPStatus string `json:"-"`
+	KYCResult string `json:"-"`
+	OFACMatch string `json:"-"`
+
+	// fields are all the fields of the profile, as returned by Galileo.
+	fields map[string]json.RawMessage
+}
+
+// IdentityFields are the names of the profile fields holding the identity verification of the customer.
+// The profile of getAccountOverview returns the fields enabled for the program, named after the identity
+// verification service of the program, so the names are configurable.
+type IdentityFields struct {
+	CIPStatus string
+	KYCResult string
+	OFACMatch string
+}
+
+// DefaultIdentityFields are the names of the identity verification fields used unless configured otherwise.
+var DefaultIdentityFields = IdentityFields{
+	CIPStatus: "cip_status",
+	KYCResult: "kyc_result",
+	OFACMatch: "ofac_match",
+}
+
+func (c *Customer) UnmarshalJSON(data []byte) error {
+	type customer Customer
+
+	var rv customer
+	err := json.Unmarshal(data, &rv)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, &rv.fields)
+	if err != nil {
+		return err
+	}
+
+	*c = Customer(rv)
+	c.setIdentity(DefaultIdentityFields)
+
+	return nil
+}
+
+// setIdentity sets the identity verification of the customer from the given profile fields.
+func (c *Customer) setIdentity(names IdentityFields) {
+	c.CIPStatus = c.field(names.CIPStatus)
+	c.KYCResult = c.field(names.KYCResult)
+	c.OFACMatch = c.field(names.OFACMatch)
+}
+
+// field returns a scalar field of the profile as a string, or an empty string if it is missing or null.
+func (c *Customer) field(name string) string {
+	raw, ok := c.fields[name]
+	if !ok {
+		return ""
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil || value == nil {
+		return ""
+	}
+
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(value)
+}
+
+// passedVerifications are the CIP statuses and KYC results of a successful verification.
+var passedVerifications = map[string]bool{
+	"pass":     true,
+	"passed":   true,
+	"verified": true,
+	"approved": true,
+}
+
+// IdentityVerified reports whether the customer passed both the CIP and the KYC verifications and did not match the OFAC list.
+func (c *Customer) IdentityVerified() bool {
+	if !passedVerifications[strings.ToLower(c.CIPStatus)] || !passedVerifications[strings.ToLower(c.KYCResult)] {
+		return false
+	}
+
+	switch strings.ToLower(c.OFACMatch) {
+	case "", "n", "no", "false", "0":
+		return true
+	default:
+		return false
+	}
+}
+
+// ProfileUpdate holds the customer profile fields changed by an account update, empty fields are left unchanged.
+type ProfileUpdate struct {
+	Email       string
+	HomePhone   string
+	MobilePhone string
+	Address1    string
+	Address2    string
+	City        string
+	State       string
+	PostalCode  string
+	CountryCode string
+}
+
+// formValues returns the fields of the update by updateAccount parameter.
+func (u *ProfileUpdate) formValues() map[string]string {
+	return map[string]string{
+		"email":       u.Email,
+		"homePhone":   u.HomePhone,
+		"mobilePhone": u.MobilePhone,
+		"address1":    u.Address1,
+		"address2":    u.Address2,
+		"city":        u.City,
+		"state":       u.State,
+		"postalCode":  u.PostalCode,
+		"countryCode": u.CountryCode,
+	}
 }
 
 type Group struct {
@@ -62,3 +198,44 @@ type GroupToAccounts struct {
 	GroupID    string   `json:"group_id"`
 	AccountIDs []string `json:"pmt_ref_no"`
 }
+
+// Membership is the membership of an account in a group, its fields are named so the two IDs are not swapped.
+type Membership struct {
+	AccountID string
+	GroupID   string
+}
+
+// CardOperation is the modifyStatus type of an operation on the card of an account.
+type CardOperation string
+
+const (
+	CardReissue       CardOperation = "12"
+	CardResetPIN      CardOperation = "17"
+	CardLostReplace   CardOperation = "2"
+	CardStolenReplace CardOperation = "3"
+)
+
+// CardStatus identifies the card of an account after a card operation.
+// Operations replacing the card may move it to a new account PRN.
+type CardStatus struct {
+	AccountNo    string `json:"pmt_ref_no"`
+	CardID       string `json:"card_id"`
+	NewAccountNo string `json:"new_pmt_ref_no"`
+	NewCardID    string `json:"new_card_id"`
+}
+
+// Transaction is a transaction posted to an account.
+type Transaction struct {
+	ID        string `json:"trans_id"`
+	Timestamp string `json:"post_ts"`
+	Amount    string `json:"amt"`
+	MCC       string `json:"mcc"`
+}
+
+// Authorization is an authorization request on the card of an account, whether or not it was posted since.
+type Authorization struct {
+	ID        string `json:"auth_id"`
+	Timestamp string `json:"auth_ts"`
+	Amount    string `json:"amt"`
+	MCC       string `json:"mcc"`
+}
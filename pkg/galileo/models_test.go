@@ -0,0 +1,45 @@
+package galileo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCustomerIdentityFields(t *testing.T) {
+	profile := `{
+		"first_name": "Jane",
+		"cip_status": "Pass",
+		"kyc_result": "approved",
+		"ofac_match": "N",
+		"idv_status": "failed",
+		"watchlist_hit": true
+	}`
+
+	var customer Customer
+	if err := json.Unmarshal([]byte(profile), &customer); err != nil {
+		t.Fatalf("failed to unmarshal customer: %v", err)
+	}
+
+	if customer.FirstName != "Jane" {
+		t.Errorf("first name = %q, want Jane", customer.FirstName)
+	}
+
+	if !customer.IdentityVerified() {
+		t.Errorf("customer with the default identity fields %+v is not verified", customer)
+	}
+
+	// fields not given keep their default name
+	customer.setIdentity(IdentityFields{
+		CIPStatus: "idv_status",
+		KYCResult: DefaultIdentityFields.KYCResult,
+		OFACMatch: "watchlist_hit",
+	})
+
+	if customer.CIPStatus != "failed" || customer.KYCResult != "approved" || customer.OFACMatch != "true" {
+		t.Errorf("identity = %q, %q, %q", customer.CIPStatus, customer.KYCResult, customer.OFACMatch)
+	}
+
+	if customer.IdentityVerified() {
+		t.Error("customer with a failed identity verification is verified")
+	}
+}
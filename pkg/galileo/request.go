@@ -10,14 +10,23 @@ import (
 	"github.com/google/uuid"
 )
 
+const redactedValue = "REDACTED"
+
+// credentialFormKeys are the form values authenticating a request, never logged.
+var credentialFormKeys = map[string]bool{
+	"apiLogin":    true,
+	"apiTransKey": true,
+}
+
 type FormData struct {
-	APILogin    string
-	APITransKey string
-	ProviderID  string
-	AccountNo   string
-	GroupID     string
-	GroupIDs    []string
-	AccountIDs  []string
+	APILogin         string
+	APITransKey      string
+	ProviderID       string
+	AccountNo        string
+	PrimaryAccountNo string
+	GroupID          string
+	GroupIDs         []string
+	AccountIDs       []string
 }
 
 type PaginationVars struct {
@@ -59,6 +68,11 @@ func prepareForm(data *FormData) *url.Values {
 		form.Set("accountNo", data.AccountNo)
 	}
 
+	// set primary account, if provided
+	if data.PrimaryAccountNo != "" {
+		form.Set("primaryAccountNo", data.PrimaryAccountNo)
+	}
+
 	// set group id, if provided
 	if data.GroupID != "" {
 		form.Set("groupId", data.GroupID)
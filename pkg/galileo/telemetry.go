@@ -0,0 +1,171 @@
+package galileo
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/metrics"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer and meter of the connector.
+// Spans and metrics are exported through the global providers configured by the SDK.
+const instrumentationName = "baton-galileo-ft"
+
+const (
+	requestDurationMetric = "galileo_request_duration"
+	requestErrorsMetric   = "galileo_request_errors"
+	requestBytesMetric    = "galileo_request_bytes"
+	responseBytesMetric   = "galileo_response_bytes"
+)
+
+// telemetry records a span and metrics for every Galileo API call.
+type telemetry struct {
+	tracer        trace.Tracer
+	duration      metrics.Int64Histogram
+	errors        metrics.Int64Counter
+	requestBytes  metrics.Int64Counter
+	responseBytes metrics.Int64Counter
+
+	mu sync.Mutex
+	// failures counts the consecutive failures of the requests failing so far, by request key,
+	// so retries of a failed request by the syncer are told apart from first attempts.
+	failures map[string]int
+}
+
+func newTelemetry() *telemetry {
+	h := metrics.NewOtelHandler(context.Background(), otel.GetMeterProvider(), instrumentationName)
+
+	return &telemetry{
+		tracer:        otel.Tracer(instrumentationName),
+		duration:      h.Int64Histogram(requestDurationMetric, "Duration of requests to the Galileo API", metrics.Milliseconds),
+		errors:        h.Int64Counter(requestErrorsMetric, "Failed requests to the Galileo API by Galileo status", metrics.Dimensionless),
+		requestBytes:  h.Int64Counter(requestBytesMetric, "Bytes sent to the Galileo API", metrics.Bytes),
+		responseBytes: h.Int64Counter(responseBytesMetric, "Bytes received from the Galileo API", metrics.Bytes),
+		failures:      make(map[string]int),
+	}
+}
+
+// apiCall is a single call to a Galileo endpoint being instrumented.
+type apiCall struct {
+	t        *telemetry
+	span     trace.Span
+	start    time.Time
+	endpoint string
+	provider string
+	key      string
+	response uhttp.WrapperResponse
+}
+
+// startCall starts the span of a call to the given endpoint.
+// The key identifies the request regardless of its transaction ID, the attempt of the call is one more than
+// the number of consecutive failures of the same request.
+func (t *telemetry) startCall(ctx context.Context, endpoint, provider, key string) (context.Context, *apiCall) {
+	t.mu.Lock()
+	attempt := t.failures[key] + 1
+	t.mu.Unlock()
+
+	ctx, span := t.tracer.Start(
+		ctx,
+		"galileo."+path.Base(endpoint),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("galileo.endpoint", endpoint),
+			attribute.String("galileo.provider", provider),
+			attribute.Int("galileo.attempt", attempt),
+		),
+	)
+
+	return ctx, &apiCall{
+		t:        t,
+		span:     span,
+		start:    time.Now(),
+		endpoint: endpoint,
+		provider: provider,
+		key:      key,
+	}
+}
+
+// captureResponse keeps the status and body of the response for the metrics of the call.
+func (a *apiCall) captureResponse() uhttp.DoOption {
+	return func(resp *uhttp.WrapperResponse) error {
+		a.response = *resp
+		return nil
+	}
+}
+
+// cacheHit ends the call, answered from the response cache without any request.
+func (a *apiCall) cacheHit() {
+	a.span.SetAttributes(attribute.Bool("galileo.cache_hit", true))
+	a.span.End()
+}
+
+// end records the metrics of the call and ends its span.
+func (a *apiCall) end(ctx context.Context, requestBytes int, err error) {
+	defer a.span.End()
+
+	tags := map[string]string{
+		"endpoint": a.endpoint,
+		"provider": a.provider,
+	}
+
+	a.t.requestBytes.Add(ctx, int64(requestBytes), tags)
+
+	// the status code is zero when no response was received, e.g. on network errors
+	if a.response.StatusCode != 0 {
+		tags["status_code"] = strconv.Itoa(a.response.StatusCode)
+		a.span.SetAttributes(attribute.Int("http.response.status_code", a.response.StatusCode))
+		a.t.responseBytes.Add(ctx, int64(len(a.response.Body)), tags)
+	}
+
+	a.t.duration.Record(ctx, time.Since(a.start).Milliseconds(), tags)
+
+	a.t.mu.Lock()
+	if err == nil {
+		delete(a.t.failures, a.key)
+	} else {
+		a.t.failures[a.key]++
+	}
+	a.t.mu.Unlock()
+
+	if err == nil {
+		return
+	}
+
+	status := a.galileoStatus()
+	a.span.SetAttributes(attribute.String("galileo.status", status))
+	a.span.RecordError(err)
+	a.span.SetStatus(codes.Error, err.Error())
+
+	tags["galileo_status"] = status
+	a.t.errors.Add(ctx, 1, tags)
+}
+
+// galileoStatus returns the Galileo status code of a failed call.
+func (a *apiCall) galileoStatus() string {
+	if a.response.StatusCode == 0 {
+		return "no_response"
+	}
+
+	var response ErrorResponse
+	err := json.Unmarshal(a.response.Body, &response)
+
+	if a.response.StatusCode >= http.StatusOK && a.response.StatusCode < http.StatusMultipleChoices && (err != nil || response.Code == 0) {
+		return "invalid_response"
+	}
+
+	if err != nil || response.Code == 0 {
+		return "unknown"
+	}
+
+	return strconv.FormatUint(uint64(response.Code), 10)
+}
@@ -0,0 +1,164 @@
+package galileo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTracedClient returns a client of a Galileo stand-in whose spans are recorded by the returned exporter.
+// The stand-in answers getAccountOverview, failing for the account "failing" as Galileo does for unknown accounts.
+func newTracedClient(t *testing.T) (*Client, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path != AccountOverviewEndpoint || r.FormValue("accountNo") == "failing" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status_code": 401, "status": "Account not found"}`))
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"status_code": 0, "response_data": {"profile": {"first_name": "Jane", "last_name": "Doe"}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(
+		server.Client(),
+		&Config{
+			Label:       "test",
+			Environment: string(EnvironmentCustom),
+			BaseURL:     server.URL,
+			APILogin:    "login",
+			APITransKey: "key",
+			ProviderID:  "provider",
+		},
+		WithCache(NewResponseCache(NewMemoryCacheStore(DefaultCacheMaxEntries), time.Minute)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	return client, exporter
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	rv := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		rv[kv.Key] = kv.Value
+	}
+
+	return rv
+}
+
+func TestClientSpans(t *testing.T) {
+	ctx := context.Background()
+	client, exporter := newTracedClient(t)
+
+	// the second call of the same account is answered from the cache
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetCustomer(ctx, "active"); err != nil {
+			t.Fatalf("GetCustomer() error = %v", err)
+		}
+	}
+
+	// the failing account is retried once
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetCustomer(ctx, "failing"); err == nil {
+			t.Fatal("GetCustomer() expected an error")
+		}
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 4 {
+		t.Fatalf("got %d spans, want 4", len(spans))
+	}
+
+	tests := []struct {
+		name       string
+		attributes map[attribute.Key]attribute.Value
+		status     codes.Code
+	}{
+		{
+			name: "success",
+			attributes: map[attribute.Key]attribute.Value{
+				"galileo.attempt":           attribute.IntValue(1),
+				"http.response.status_code": attribute.IntValue(http.StatusOK),
+			},
+			status: codes.Unset,
+		},
+		{
+			name: "cache hit",
+			attributes: map[attribute.Key]attribute.Value{
+				"galileo.attempt":   attribute.IntValue(1),
+				"galileo.cache_hit": attribute.BoolValue(true),
+			},
+			status: codes.Unset,
+		},
+		{
+			name: "error",
+			attributes: map[attribute.Key]attribute.Value{
+				"galileo.attempt":           attribute.IntValue(1),
+				"http.response.status_code": attribute.IntValue(http.StatusBadRequest),
+				"galileo.status":            attribute.StringValue("401"),
+			},
+			status: codes.Error,
+		},
+		{
+			name: "retried error",
+			attributes: map[attribute.Key]attribute.Value{
+				"galileo.attempt": attribute.IntValue(2),
+				"galileo.status":  attribute.StringValue("401"),
+			},
+			status: codes.Error,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := spans[i]
+
+			if span.Name != "galileo.getAccountOverview" {
+				t.Errorf("span name = %q, want galileo.getAccountOverview", span.Name)
+			}
+
+			if span.Status.Code != tt.status {
+				t.Errorf("span status = %v, want %v", span.Status.Code, tt.status)
+			}
+
+			attributes := spanAttributes(span)
+			want := map[attribute.Key]attribute.Value{
+				"galileo.endpoint": attribute.StringValue(AccountOverviewEndpoint),
+				"galileo.provider": attribute.StringValue("test"),
+			}
+			for k, v := range tt.attributes {
+				want[k] = v
+			}
+
+			for k, v := range want {
+				if got, ok := attributes[k]; !ok || got != v {
+					t.Errorf("attribute %s = %v, want %v", k, got.Emit(), v.Emit())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package galileo
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/metrics"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultMaxIdleConnsPerHost keeps enough connections open for the per-account calls of a sync,
+	// the standard library only keeps two idle connections per host.
+	DefaultMaxIdleConnsPerHost = 16
+	// DefaultIdleConnTimeout is shorter than the idle timeout of most forward proxies (Squid defaults to 60s),
+	// so pooled connections are not reused after the proxy closed them.
+	DefaultIdleConnTimeout = 50 * time.Second
+
+	connectionsMetric = "galileo_http_connections"
+)
+
+// TransportConfig holds the connectivity settings used for private links or proxies to Galileo.
+// Certificates, keys and CA bundles can be given either as file paths or as inline PEM.
+type TransportConfig struct {
+	ClientCert string
+	ClientKey  string
+	CABundle   string
+	HTTPProxy  string
+	HTTPSProxy string
+	// ProxyUsername and ProxyPassword authenticate to the proxy, including the CONNECT requests of HTTPS tunnels.
+	// They take precedence over credentials embedded in the proxy URLs.
+	ProxyUsername string
+	ProxyPassword string
+	// MaxIdleConnsPerHost and IdleConnTimeout tune the connection pool, zero values use the defaults.
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+}
+
+// NewHTTPClient returns the HTTP client used to reach the Galileo API with the given connectivity settings.
+// A dedicated transport is used rather than the SDK one, which resolves proxies from the environment only
+// and is recreated periodically, dropping the pooled connections. It is wrapped to log every request
+// as the SDK transport does, and the client is then wrapped by the SDK base client of the Galileo client.
+func NewHTTPClient(ctx context.Context, config *TransportConfig) (*http.Client, error) {
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := config.proxy()
+	if err != nil {
+		return nil, err
+	}
+
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unexpected default transport type %T", http.DefaultTransport)
+	}
+
+	transport = transport.Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	transport.IdleConnTimeout = DefaultIdleConnTimeout
+
+	if proxy != nil {
+		transport.Proxy = proxy
+	}
+
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+
+	if transport.MaxIdleConns < transport.MaxIdleConnsPerHost {
+		transport.MaxIdleConns = transport.MaxIdleConnsPerHost
+	}
+
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+
+	return &http.Client{
+		Transport: &requestLogger{next: newConnTracker(ctx, transport)},
+	}, nil
+}
+
+func (tc *TransportConfig) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if tc.ClientCert != "" || tc.ClientKey != "" {
+		certPEM, err := loadPEM(tc.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		keyPEM, err := loadPEM(tc.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client key: %w", err)
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate or key: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if tc.CABundle != "" {
+		caPEM, err := loadPEM(tc.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA bundle: %w", err)
+		}
+
+		// The private CA is trusted in addition to the system roots.
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("CA bundle does not contain any PEM certificate")
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
+
+// proxy returns the proxy function of the transport, or nil if no proxy is configured.
+// The HTTP proxy is also used for HTTPS requests when no HTTPS proxy is set.
+func (tc *TransportConfig) proxy() (func(*http.Request) (*url.URL, error), error) {
+	if tc.HTTPProxy == "" && tc.HTTPSProxy == "" {
+		if tc.ProxyUsername != "" || tc.ProxyPassword != "" {
+			return nil, fmt.Errorf("proxy credentials are set without a proxy")
+		}
+
+		return nil, nil
+	}
+
+	var httpProxy, httpsProxy *url.URL
+	var err error
+
+	if tc.HTTPProxy != "" {
+		httpProxy, err = tc.parseProxy(tc.HTTPProxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTTP proxy: %w", err)
+		}
+	}
+
+	if tc.HTTPSProxy != "" {
+		httpsProxy, err = tc.parseProxy(tc.HTTPSProxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTTPS proxy: %w", err)
+		}
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if req.URL.Scheme == "https" && httpsProxy != nil {
+			return httpsProxy, nil
+		}
+
+		return httpProxy, nil
+	}, nil
+}
+
+// parseProxy returns the URL of a proxy with the configured proxy credentials.
+// The transport sends them in the Proxy-Authorization header of proxied requests and CONNECT requests.
+func (tc *TransportConfig) parseProxy(value string) (*url.URL, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute URL", value)
+	}
+
+	if tc.ProxyUsername != "" {
+		u.User = url.UserPassword(tc.ProxyUsername, tc.ProxyPassword)
+	}
+
+	return u, nil
+}
+
+// connTracker counts the new and reused connections of the requests sent to Galileo.
+type connTracker struct {
+	next    http.RoundTripper
+	counter metrics.Int64Counter
+	opened  atomic.Int64
+	reused  atomic.Int64
+}
+
+func newConnTracker(ctx context.Context, next http.RoundTripper) *connTracker {
+	h := metrics.NewOtelHandler(ctx, otel.GetMeterProvider(), instrumentationName)
+
+	return &connTracker{
+		next:    next,
+		counter: h.Int64Counter(connectionsMetric, "Connections used by requests to the Galileo API", metrics.Dimensionless),
+	}
+}
+
+func (t *connTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.record(ctx, info.Reused)
+		},
+	}
+
+	return t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(ctx, trace)))
+}
+
+func (t *connTracker) record(ctx context.Context, reused bool) {
+	t.counter.Add(ctx, 1, map[string]string{"reused": fmt.Sprint(reused)})
+
+	if reused {
+		t.reused.Add(1)
+		return
+	}
+
+	ctxzap.Extract(ctx).Debug(
+		"galileo-ft-connector: opened new connection",
+		zap.Int64("opened", t.opened.Add(1)),
+		zap.Int64("reused", t.reused.Load()),
+	)
+}
+
+// requestLogger logs every request sent to Galileo with the fields and levels of the SDK transport,
+// which cannot be given a transport of its own.
+type requestLogger struct {
+	next http.RoundTripper
+}
+
+func (t *requestLogger) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
+	resp, err := t.next.RoundTrip(req)
+
+	fields := []zap.Field{
+		zap.String("http.method", req.Method),
+		zap.String("http.url_details.host", req.URL.Host),
+		zap.String("http.url_details.path", req.URL.Path),
+		zap.Duration("duration", time.Since(start)),
+	}
+
+	if resp != nil {
+		fields = append(fields, zap.Int("http.status_code", resp.StatusCode))
+	}
+
+	l := ctxzap.Extract(req.Context())
+	switch {
+	case err != nil:
+		l.Error("HTTP request failed", append(fields, zap.Error(err))...)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		l.Warn("HTTP request server error", fields...)
+	case resp.StatusCode >= http.StatusBadRequest:
+		l.Debug("HTTP request client error", fields...)
+	default:
+		l.Debug("HTTP request complete", fields...)
+	}
+
+	return resp, err
+}
+
+// loadPEM returns the value itself if it is inline PEM, otherwise the content of the file it points to.
+func loadPEM(value string) ([]byte, error) {
+	if value == "" {
+		return nil, fmt.Errorf("value is empty")
+	}
+
+	if strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+
+	return os.ReadFile(value)
+}
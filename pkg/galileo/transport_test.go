@@ -0,0 +1,259 @@
+package galileo
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCertificate returns a self-signed client certificate and its key, both PEM encoded.
+func testCertificate(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "baton-galileo-ft"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return string(certPEM), string(keyPEM)
+}
+
+// newMTLSServer starts a TLS server requiring a client certificate signed by the given certificate.
+func newMTLSServer(t *testing.T, clientCertPEM string) (*httptest.Server, string) {
+	t.Helper()
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM([]byte(clientCertPEM)) {
+		t.Fatal("failed to add client certificate to the pool")
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	return server, string(caPEM)
+}
+
+func TestNewHTTPClientMutualTLS(t *testing.T) {
+	ctx := context.Background()
+	certPEM, keyPEM := testCertificate(t)
+	server, caPEM := newMTLSServer(t, certPEM)
+
+	tests := []struct {
+		name    string
+		config  *TransportConfig
+		wantErr bool
+	}{
+		{
+			name:   "client certificate and custom CA",
+			config: &TransportConfig{ClientCert: certPEM, ClientKey: keyPEM, CABundle: caPEM},
+		},
+		{
+			name:    "no client certificate",
+			config:  &TransportConfig{CABundle: caPEM},
+			wantErr: true,
+		},
+		{
+			name:    "no custom CA",
+			config:  &TransportConfig{ClientCert: certPEM, ClientKey: keyPEM},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewHTTPClient(ctx, tt.config)
+			if err != nil {
+				t.Fatalf("NewHTTPClient() error = %v", err)
+			}
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			resp, err := client.Do(req)
+			if resp != nil {
+				resp.Body.Close()
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected the request to be rejected")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("unexpected status code %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestNewHTTPClientInvalidTLSConfig(t *testing.T) {
+	ctx := context.Background()
+	certPEM, _ := testCertificate(t)
+
+	tests := []struct {
+		name   string
+		config *TransportConfig
+	}{
+		{
+			name:   "certificate without key",
+			config: &TransportConfig{ClientCert: certPEM},
+		},
+		{
+			name:   "CA bundle without certificate",
+			config: &TransportConfig{CABundle: "-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n"},
+		},
+		{
+			name:   "proxy credentials without proxy",
+			config: &TransportConfig{ProxyUsername: "user", ProxyPassword: "secret"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHTTPClient(ctx, tt.config)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+// proxyRecorder is a forward proxy stand-in recording the requests it receives.
+// It answers proxied requests itself and refuses to open tunnels.
+type proxyRecorder struct {
+	mu       sync.Mutex
+	methods  []string
+	authz    []string
+	wantAuth string
+}
+
+func (p *proxyRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.methods = append(p.methods, r.Method)
+	p.authz = append(p.authz, r.Header.Get("Proxy-Authorization"))
+	p.mu.Unlock()
+
+	if r.Header.Get("Proxy-Authorization") != p.wantAuth {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+		return
+	}
+
+	if r.Method == http.MethodConnect {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestNewHTTPClientProxyAuth(t *testing.T) {
+	ctx := context.Background()
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+
+	recorder := &proxyRecorder{wantAuth: wantAuth}
+	proxy := httptest.NewServer(recorder)
+	t.Cleanup(proxy.Close)
+
+	client, err := NewHTTPClient(ctx, &TransportConfig{
+		HTTPProxy:     proxy.URL,
+		ProxyUsername: "user",
+		ProxyPassword: "secret",
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPClient() error = %v", err)
+	}
+
+	// plain requests are sent to the proxy with the credentials
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://galileo.invalid/intserv/4.0/ping", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code %d, the proxy did not accept the credentials", resp.StatusCode)
+	}
+
+	// HTTPS requests open a tunnel through the same proxy, authenticated with the same credentials
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, "https://galileo.invalid/intserv/4.0/ping", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	resp, err = client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the tunnel to be refused")
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if len(recorder.methods) != 2 || recorder.methods[1] != http.MethodConnect {
+		t.Fatalf("unexpected proxied requests %v", recorder.methods)
+	}
+
+	for i, authz := range recorder.authz {
+		if authz != wantAuth {
+			t.Errorf("request %d sent Proxy-Authorization %q, want %q", i, authz, wantAuth)
+		}
+	}
+}